@@ -1135,7 +1135,13 @@ func (c *Config) addInput(name string, table *ast.Table) error {
 			return err
 		}
 		t.SetParserFunc(func() (parsers.Parser, error) {
-			return parsers.NewParser(config)
+			parser, err := parsers.NewParser(config)
+			if err != nil {
+				return nil, err
+			}
+			logger := models.NewLogger("parsers", config.DataFormat, name)
+			models.SetLoggerOnPlugin(parser, logger)
+			return parser, nil
 		})
 	}
 
@@ -1308,7 +1314,8 @@ func (c *Config) buildParser(name string, tbl *ast.Table) (parsers.Parser, error
 
 func (c *Config) getParserConfig(name string, tbl *ast.Table) (*parsers.Config, error) {
 	pc := &parsers.Config{
-		JSONStrict: true,
+		JSONStrict:   true,
+		JSONV2Strict: true,
 	}
 
 	c.getFieldString(tbl, "data_format", &pc.DataFormat)
@@ -1407,6 +1414,30 @@ func (c *Config) getParserConfig(name string, tbl *ast.Table) (*parsers.Config,
 	}
 
 	//for JSONPath parser
+	c.getFieldBool(tbl, "json_v2_all_fields_as_strings", &pc.JSONV2AllFieldsAsStrings)
+	c.getFieldBool(tbl, "json_v2_strict", &pc.JSONV2Strict)
+	// Strict mode is the default, so objects matched by a path are only an error if it is set explicitly
+	if _, ok := tbl.Fields["json_v2_strict"]; ok {
+		pc.JSONV2StrictObjects = pc.JSONV2Strict
+	}
+	c.getFieldDuration(tbl, "json_v2_time_precision", &pc.JSONV2TimePrecision)
+	c.getFieldBool(tbl, "json_v2_type_inference", &pc.JSONV2TypeInference)
+	c.getFieldString(tbl, "json_v2_on_error", &pc.JSONV2OnError)
+	c.getFieldBool(tbl, "json_v2_force_float", &pc.JSONV2ForceFloat)
+	c.getFieldString(tbl, "json_v2_parse_errors_measurement", &pc.JSONV2ParseErrorsMeasurement)
+	c.getFieldInt(tbl, "json_v2_parse_errors_snippet_length", &pc.JSONV2ParseErrorsSnippetLength)
+	c.getFieldInt(tbl, "json_v2_max_body_size", &pc.JSONV2MaxBodySize)
+	c.getFieldInt(tbl, "json_v2_max_depth", &pc.JSONV2MaxDepth)
+	c.getFieldString(tbl, "json_v2_strip_prefix_regex", &pc.JSONV2StripPrefixRegex)
+	c.getFieldBool(tbl, "json_v2_trim_space", &pc.JSONV2TrimSpace)
+	c.getFieldBool(tbl, "json_v2_dedup", &pc.JSONV2Dedup)
+	c.getFieldString(tbl, "json_v2_invalid_utf8", &pc.JSONV2InvalidUTF8)
+	c.getFieldString(tbl, "json_v2_format", &pc.JSONV2Format)
+	c.getFieldInt(tbl, "json_v2_max_metrics", &pc.JSONV2MaxMetrics)
+	c.getFieldString(tbl, "json_v2_max_metrics_mode", &pc.JSONV2MaxMetricsMode)
+	c.getFieldBool(tbl, "json_v2_json_lines", &pc.JSONV2JSONLines)
+	c.getFieldString(tbl, "json_v2_on_missing", &pc.JSONV2OnMissing)
+	c.getFieldString(tbl, "json_v2_unicode_normalization", &pc.JSONV2UnicodeNormalization)
 	if node, ok := tbl.Fields["json_v2"]; ok {
 		if metricConfigs, ok := node.([]*ast.Table); ok {
 			pc.JSONV2Config = make([]parsers.JSONV2Config, len(metricConfigs))
@@ -1419,27 +1450,63 @@ func (c *Config) getParserConfig(name string, tbl *ast.Table) (*parsers.Config,
 				c.getFieldString(metricConfig, "measurement_name_path", &mc.MeasurementNamePath)
 				c.getFieldString(metricConfig, "timestamp_path", &mc.TimestampPath)
 				c.getFieldString(metricConfig, "timestamp_format", &mc.TimestampFormat)
+				c.getFieldStringSlice(metricConfig, "timestamp_formats", &mc.TimestampFormats)
 				c.getFieldString(metricConfig, "timestamp_timezone", &mc.TimestampTimezone)
-
-				if fieldConfigs, ok := metricConfig.Fields["field"]; ok {
-					if fieldConfigs, ok := fieldConfigs.([]*ast.Table); ok {
-						for _, fieldconfig := range fieldConfigs {
-							var f json_v2.DataSet
-							c.getFieldString(fieldconfig, "path", &f.Path)
-							c.getFieldString(fieldconfig, "rename", &f.Rename)
-							c.getFieldString(fieldconfig, "type", &f.Type)
-							mc.Fields = append(mc.Fields, f)
+				c.getFieldString(metricConfig, "timestamp_default", &mc.TimestampDefault)
+				c.getFieldString(metricConfig, "total_count_field", &mc.TotalCountField)
+				c.getFieldString(metricConfig, "unwrap", &mc.Unwrap)
+				c.getFieldBool(metricConfig, "emit_match_coverage", &mc.EmitMatchCoverage)
+				c.getFieldString(metricConfig, "expiry_tag", &mc.ExpiryTag)
+				c.getFieldDuration(metricConfig, "expiry_after", &mc.ExpiryAfter)
+				mc.DropEmptyMetrics = true
+				c.getFieldBool(metricConfig, "drop_empty_metrics", &mc.DropEmptyMetrics)
+				c.getFieldString(metricConfig, "index_tag", &mc.IndexTag)
+				c.getFieldString(metricConfig, "default_field_name", &mc.DefaultFieldName)
+				c.getFieldStringMap(metricConfig, "default_tags", &mc.DefaultTags)
+				c.getFieldString(metricConfig, "root_path", &mc.RootPath)
+				c.getFieldInt(metricConfig, "sample_every_n", &mc.SampleEveryN)
+				c.getFieldBool(metricConfig, "emit_count", &mc.EmitCount)
+				c.getFieldString(metricConfig, "count_measurement", &mc.CountMeasurement)
+				c.getFieldString(metricConfig, "hash_tag", &mc.HashTag)
+				c.getFieldStringSlice(metricConfig, "hash_keys", &mc.HashKeys)
+				c.getFieldString(metricConfig, "hash_algorithm", &mc.HashAlgorithm)
+				c.getFieldString(metricConfig, "tag_precedence", &mc.TagPrecedence)
+
+				c.getJSONV2Fields(metricConfig, &mc.Fields)
+				c.getJSONV2Tags(metricConfig, &mc.Tags)
+
+				if groupConfigs, ok := metricConfig.Fields["group"]; ok {
+					if groupConfigs, ok := groupConfigs.([]*ast.Table); ok {
+						for _, groupConfig := range groupConfigs {
+							var g json_v2.Group
+							c.getFieldString(groupConfig, "root_path", &g.RootPath)
+							c.getFieldString(groupConfig, "prefix", &g.Prefix)
+							c.getJSONV2Fields(groupConfig, &g.Fields)
+							c.getJSONV2Tags(groupConfig, &g.Tags)
+							mc.Groups = append(mc.Groups, g)
 						}
 					}
 				}
-				if fieldConfigs, ok := metricConfig.Fields["tag"]; ok {
-					if fieldConfigs, ok := fieldConfigs.([]*ast.Table); ok {
-						for _, fieldconfig := range fieldConfigs {
-							var t json_v2.DataSet
-							c.getFieldString(fieldconfig, "path", &t.Path)
-							c.getFieldString(fieldconfig, "rename", &t.Rename)
-							t.Type = "string"
-							mc.Tags = append(mc.Tags, t)
+
+				c.getJSONV2Filters(metricConfig, "filter", &mc.Filters)
+
+				if lookupConfigs, ok := metricConfig.Fields["lookup"]; ok {
+					if lookupConfigs, ok := lookupConfigs.([]*ast.Table); ok {
+						for _, lookupConfig := range lookupConfigs {
+							var l json_v2.Lookup
+							c.getFieldString(lookupConfig, "tag", &l.Tag)
+							c.getFieldString(lookupConfig, "file", &l.File)
+							if node, ok := lookupConfig.Fields["table"]; ok {
+								if tableConfig, ok := node.(*ast.Table); ok {
+									l.Table = make(map[string]map[string]string, len(tableConfig.Fields))
+									for key := range tableConfig.Fields {
+										var tags map[string]string
+										c.getFieldStringMap(tableConfig, key, &tags)
+										l.Table[key] = tags
+									}
+								}
+							}
+							mc.Lookups = append(mc.Lookups, l)
 						}
 					}
 				}
@@ -1451,6 +1518,7 @@ func (c *Config) getParserConfig(name string, tbl *ast.Table) (*parsers.Config,
 							c.getFieldString(objectConfig, "path", &o.Path)
 							c.getFieldString(objectConfig, "timestamp_key", &o.TimestampKey)
 							c.getFieldString(objectConfig, "timestamp_format", &o.TimestampFormat)
+							c.getFieldStringSlice(objectConfig, "timestamp_formats", &o.TimestampFormats)
 							c.getFieldString(objectConfig, "timestamp_timezone", &o.TimestampTimezone)
 							c.getFieldBool(objectConfig, "disable_prepend_keys", &o.DisablePrependKeys)
 							c.getFieldStringSlice(objectConfig, "included_keys", &o.IncludedKeys)
@@ -1570,6 +1638,12 @@ func (c *Config) missingTomlField(_ reflect.Type, key string) error {
 		"grok_timezone", "grok_unique_timestamp", "influx_max_line_bytes", "influx_sort_fields",
 		"influx_uint_support", "interval", "json_name_key", "json_query", "json_strict",
 		"json_string_fields", "json_time_format", "json_time_key", "json_timestamp_units", "json_timezone", "json_v2",
+		"json_v2_all_fields_as_strings", "json_v2_strict", "json_v2_time_precision", "json_v2_type_inference", "json_v2_on_error",
+		"json_v2_force_float",
+		"json_v2_parse_errors_measurement", "json_v2_parse_errors_snippet_length", "json_v2_max_body_size", "json_v2_max_depth",
+		"json_v2_strip_prefix_regex", "json_v2_trim_space", "json_v2_invalid_utf8", "json_v2_unicode_normalization",
+		"json_v2_dedup", "json_v2_format", "json_v2_max_metrics", "json_v2_max_metrics_mode", "json_v2_json_lines",
+		"json_v2_on_missing",
 		"metric_batch_size", "metric_buffer_limit", "name_override", "name_prefix",
 		"name_suffix", "namedrop", "namepass", "order", "pass", "period", "precision",
 		"prefix", "prometheus_export_timestamp", "prometheus_sort_metrics", "prometheus_string_as_label",
@@ -1667,6 +1741,29 @@ func (c *Config) getFieldInt64(tbl *ast.Table, fieldName string, target *int64)
 	}
 }
 
+func (c *Config) getFieldFloat(tbl *ast.Table, fieldName string, target *float64) {
+	if node, ok := tbl.Fields[fieldName]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			switch v := kv.Value.(type) {
+			case *ast.Float:
+				f, err := v.Float()
+				if err != nil {
+					c.addError(tbl, fmt.Errorf("unexpected float type %q, expecting float", v.Value))
+					return
+				}
+				*target = f
+			case *ast.Integer:
+				i, err := v.Int()
+				if err != nil {
+					c.addError(tbl, fmt.Errorf("unexpected int type %q, expecting float", v.Value))
+					return
+				}
+				*target = float64(i)
+			}
+		}
+	}
+}
+
 func (c *Config) getFieldStringSlice(tbl *ast.Table, fieldName string, target *[]string) {
 	if node, ok := tbl.Fields[fieldName]; ok {
 		if kv, ok := node.(*ast.KeyValue); ok {
@@ -1722,6 +1819,154 @@ func (c *Config) getFieldStringMap(tbl *ast.Table, fieldName string, target *map
 	}
 }
 
+func (c *Config) getFieldInt64Map(tbl *ast.Table, fieldName string, target *map[string]int64) {
+	if node, ok := tbl.Fields[fieldName]; ok {
+		if subtbl, ok := node.(*ast.Table); ok {
+			*target = make(map[string]int64, len(subtbl.Fields))
+			for name, val := range subtbl.Fields {
+				if kv, ok := val.(*ast.KeyValue); ok {
+					iAst, ok := kv.Value.(*ast.Integer)
+					if !ok {
+						c.addError(tbl, fmt.Errorf("found unexpected format while parsing %q, expecting integer values", fieldName))
+						return
+					}
+					i, err := iAst.Int()
+					if err != nil {
+						c.addError(tbl, fmt.Errorf("unexpected int type %q, expecting int", iAst.Value))
+						return
+					}
+					(*target)[name] = i
+				}
+			}
+		}
+	}
+}
+
+// getJSONV2Fields appends the fields of the json_v2 parser defined by the 'field' array of tables to the target
+func (c *Config) getJSONV2Fields(tbl *ast.Table, target *[]json_v2.DataSet) {
+	if fieldConfigs, ok := tbl.Fields["field"]; ok {
+		if fieldConfigs, ok := fieldConfigs.([]*ast.Table); ok {
+			for _, fieldconfig := range fieldConfigs {
+				var f json_v2.DataSet
+				c.getFieldString(fieldconfig, "path", &f.Path)
+				c.getFieldStringSlice(fieldconfig, "fallback_paths", &f.FallbackPaths)
+				c.getFieldString(fieldconfig, "rename", &f.Rename)
+				c.getFieldString(fieldconfig, "type", &f.Type)
+				c.getFieldBool(fieldconfig, "require_monotonic", &f.RequireMonotonic)
+				c.getFieldBool(fieldconfig, "flatten", &f.Flatten)
+				c.getFieldString(fieldconfig, "flatten_separator", &f.FlattenSeparator)
+				c.getFieldStringSlice(fieldconfig, "true_values", &f.TrueValues)
+				c.getFieldStringSlice(fieldconfig, "false_values", &f.FalseValues)
+				c.getFieldBool(fieldconfig, "strict_bool", &f.StrictBool)
+				c.getFieldString(fieldconfig, "overflow", &f.Overflow)
+				c.getFieldString(fieldconfig, "key_tag", &f.KeyTag)
+				c.getFieldString(fieldconfig, "key_split", &f.KeySplit)
+				c.getFieldStringSlice(fieldconfig, "key_split_tags", &f.KeySplitTags)
+				c.getFieldString(fieldconfig, "source_path_tag", &f.SourcePathTag)
+				c.getFieldString(fieldconfig, "sub_format", &f.SubFormat)
+				c.getFieldString(fieldconfig, "decimal_separator", &f.DecimalSeparator)
+				c.getFieldString(fieldconfig, "group_separator", &f.GroupSeparator)
+				c.getFieldBool(fieldconfig, "parse_nested_json", &f.ParseNestedJSON)
+				c.getFieldFloat(fieldconfig, "scale", &f.Scale)
+				c.getFieldFloat(fieldconfig, "offset", &f.Offset)
+				c.getFieldBool(fieldconfig, "join_array", &f.JoinArray)
+				c.getFieldString(fieldconfig, "join_separator", &f.JoinSeparator)
+				c.getFieldInt(fieldconfig, "max_matches", &f.MaxMatches)
+				c.getFieldStringSlice(fieldconfig, "include_keys", &f.IncludeKeys)
+				c.getFieldStringSlice(fieldconfig, "exclude_keys", &f.ExcludeKeys)
+				c.getJSONV2Filters(fieldconfig, "condition", &f.Conditions)
+				c.getFieldString(fieldconfig, "split", &f.Split)
+				c.getFieldStringSlice(fieldconfig, "split_names", &f.SplitNames)
+				c.getFieldStringSlice(fieldconfig, "split_types", &f.SplitTypes)
+				c.getFieldBool(fieldconfig, "empty_string_as_null", &f.EmptyStringAsNull)
+				c.getFieldString(fieldconfig, "default_value", &f.DefaultValue)
+				c.getFieldStringMap(fieldconfig, "value_map", &f.ValueMap)
+				c.getFieldBool(fieldconfig, "trim_space", &f.TrimSpace)
+				c.getFieldBool(fieldconfig, "lowercase", &f.Lowercase)
+				c.getFieldBool(fieldconfig, "keep_raw", &f.KeepRaw)
+				c.getFieldString(fieldconfig, "raw_suffix", &f.RawSuffix)
+				c.getFieldBool(fieldconfig, "columnar", &f.Columnar)
+				c.getFieldBool(fieldconfig, "delta", &f.Delta)
+				c.getFieldString(fieldconfig, "delta_reset", &f.DeltaReset)
+				c.getFieldString(fieldconfig, "aggregate", &f.Aggregate)
+				c.getFieldString(fieldconfig, "value", &f.Value)
+				c.getFieldBool(fieldconfig, "optional", &f.Optional)
+				c.getFieldInt64Map(fieldconfig, "enum_map", &f.EnumMap)
+				var bitfields map[string]string
+				c.getFieldStringMap(fieldconfig, "bitfields", &bitfields)
+				for bit, name := range bitfields {
+					b, err := strconv.Atoi(bit)
+					if err != nil {
+						c.addError(fieldconfig, fmt.Errorf("invalid bit %q in bitfields: %w", bit, err))
+						continue
+					}
+					if f.Bitfields == nil {
+						f.Bitfields = make(map[int]string, len(bitfields))
+					}
+					f.Bitfields[b] = name
+				}
+				*target = append(*target, f)
+			}
+		}
+	}
+}
+
+// getJSONV2Tags appends the tags of the json_v2 parser defined by the 'tag' array of tables to the target
+func (c *Config) getJSONV2Tags(tbl *ast.Table, target *[]json_v2.DataSet) {
+	if fieldConfigs, ok := tbl.Fields["tag"]; ok {
+		if fieldConfigs, ok := fieldConfigs.([]*ast.Table); ok {
+			for _, fieldconfig := range fieldConfigs {
+				var t json_v2.DataSet
+				c.getFieldString(fieldconfig, "path", &t.Path)
+				c.getFieldStringSlice(fieldconfig, "fallback_paths", &t.FallbackPaths)
+				c.getFieldString(fieldconfig, "rename", &t.Rename)
+				c.getFieldString(fieldconfig, "type", &t.Type)
+				c.getFieldBool(fieldconfig, "flatten", &t.Flatten)
+				c.getFieldString(fieldconfig, "flatten_separator", &t.FlattenSeparator)
+				c.getFieldBool(fieldconfig, "parse_nested_json", &t.ParseNestedJSON)
+				c.getFieldBool(fieldconfig, "join_array", &t.JoinArray)
+				c.getFieldString(fieldconfig, "join_separator", &t.JoinSeparator)
+				c.getFieldInt(fieldconfig, "max_matches", &t.MaxMatches)
+				c.getFieldStringSlice(fieldconfig, "include_keys", &t.IncludeKeys)
+				c.getFieldStringSlice(fieldconfig, "exclude_keys", &t.ExcludeKeys)
+				c.getFieldString(fieldconfig, "sub_format", &t.SubFormat)
+				c.getFieldBool(fieldconfig, "required", &t.Required)
+				c.getFieldString(fieldconfig, "source_path_tag", &t.SourcePathTag)
+				c.getJSONV2Filters(fieldconfig, "condition", &t.Conditions)
+				c.getFieldString(fieldconfig, "split", &t.Split)
+				c.getFieldStringSlice(fieldconfig, "split_names", &t.SplitNames)
+				c.getFieldBool(fieldconfig, "empty_string_as_null", &t.EmptyStringAsNull)
+				c.getFieldString(fieldconfig, "default_value", &t.DefaultValue)
+				c.getFieldStringMap(fieldconfig, "value_map", &t.ValueMap)
+				c.getFieldBool(fieldconfig, "trim_space", &t.TrimSpace)
+				c.getFieldBool(fieldconfig, "lowercase", &t.Lowercase)
+				c.getFieldString(fieldconfig, "value", &t.Value)
+				c.getFieldBool(fieldconfig, "optional", &t.Optional)
+				c.getFieldInt64Map(fieldconfig, "enum_map", &t.EnumMap)
+				if t.Type == "" {
+					t.Type = "string"
+				}
+				*target = append(*target, t)
+			}
+		}
+	}
+}
+
+// getJSONV2Filters appends the comparisons of the json_v2 parser defined by the array of tables to the target
+func (c *Config) getJSONV2Filters(tbl *ast.Table, fieldName string, target *[]json_v2.Filter) {
+	if node, ok := tbl.Fields[fieldName]; ok {
+		if filterConfigs, ok := node.([]*ast.Table); ok {
+			for _, filterConfig := range filterConfigs {
+				var f json_v2.Filter
+				c.getFieldString(filterConfig, "path", &f.Path)
+				c.getFieldString(filterConfig, "operator", &f.Operator)
+				c.getFieldString(filterConfig, "value", &f.Value)
+				*target = append(*target, f)
+			}
+		}
+	}
+}
+
 func keys(m map[string]bool) []string {
 	result := []string{}
 	for k := range m {
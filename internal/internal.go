@@ -225,18 +225,31 @@ func CompressWithGzip(data io.Reader) (io.ReadCloser, error) {
 	return pipeReader, err
 }
 
+const (
+	// HTTPDate is the layout of the HTTP-date as used in HTTP headers, see RFC 7231
+	// HTTP dates are always in UTC, so they are parsed in UTC regardless of the location
+	HTTPDate = "Mon, 02 Jan 2006 15:04:05 GMT"
+	// RFC2822 is the layout of the date and time specification of RFC 2822, an alias of time.RFC1123Z
+	RFC2822 = time.RFC1123Z
+)
+
 // ParseTimestamp parses a Time according to the standard Telegraf options.
 // These are generally displayed in the toml similar to:
 //   json_time_key= "timestamp"
 //   json_time_format = "2006-01-02T15:04:05Z07:00"
 //   json_timezone = "America/Los_Angeles"
 //
-// The format can be one of "unix", "unix_ms", "unix_us", "unix_ns", or a Go
-// time layout suitable for time.Parse.
+// The format can be one of "unix", "unix_ms", "unix_us", "unix_ns", "unix_auto", the name
+// of a predefined layout like "rfc3339" or "http", or a Go time layout
+// suitable for time.Parse.
 //
 // When using the "unix" format, a optional fractional component is allowed.
 // Specific unix time precisions cannot have a fractional component.
 //
+// The "unix_auto" format guesses the precision from the magnitude of the
+// integer component: values of at least 1e18 are nanoseconds, at least 1e15
+// microseconds, at least 1e12 milliseconds and smaller values are seconds.
+//
 // Unix times may be an int64, float64, or string.  When using a Go format
 // string the timestamp must be a string.
 //
@@ -245,7 +258,7 @@ func CompressWithGzip(data io.Reader) (io.ReadCloser, error) {
 // UTC location.
 func ParseTimestamp(format string, timestamp interface{}, location string) (time.Time, error) {
 	switch format {
-	case "unix", "unix_ms", "unix_us", "unix_ns":
+	case "unix", "unix_ms", "unix_us", "unix_ns", "unix_auto":
 		return parseUnix(format, timestamp)
 	default:
 		if location == "" {
@@ -261,7 +274,12 @@ func parseUnix(format string, timestamp interface{}) (time.Time, error) {
 		return time.Unix(0, 0), err
 	}
 
-	switch strings.ToLower(format) {
+	format = strings.ToLower(format)
+	if format == "unix_auto" {
+		format = unixPrecision(integer)
+	}
+
+	switch format {
 	case "unix":
 		return time.Unix(integer, fractional).UTC(), nil
 	case "unix_ms":
@@ -275,6 +293,22 @@ func parseUnix(format string, timestamp interface{}) (time.Time, error) {
 	}
 }
 
+// unixPrecision returns the unix format matching the magnitude of the timestamp
+func unixPrecision(integer int64) string {
+	if integer < 0 {
+		integer = -integer
+	}
+	switch {
+	case integer >= 1e18:
+		return "unix_ns"
+	case integer >= 1e15:
+		return "unix_us"
+	case integer >= 1e12:
+		return "unix_ms"
+	}
+	return "unix"
+}
+
 // Returns the integers before and after an optional decimal point.  Both '.'
 // and ',' are supported for the decimal point.  The timestamp can be an int64,
 // float64, or string.
@@ -366,6 +400,11 @@ func parseTime(format string, timestamp interface{}, location string) (time.Time
 			format = time.RFC1123
 		case "rfc1123z":
 			format = time.RFC1123Z
+		case "http":
+			format = HTTPDate
+			loc = time.UTC
+		case "rfc2822":
+			format = RFC2822
 		case "rfc3339":
 			format = time.RFC3339
 		case "rfc3339nano":
@@ -496,6 +496,30 @@ func TestParseTimestamp(t *testing.T) {
 			timestamp: "1568338208000000500",
 			expected:  rfc3339("2019-09-13T01:30:08.000000500Z"),
 		},
+		{
+			name:      "unix auto seconds",
+			format:    "unix_auto",
+			timestamp: "1568338208.5",
+			expected:  rfc3339("2019-09-13T01:30:08.500Z"),
+		},
+		{
+			name:      "unix auto milliseconds",
+			format:    "unix_auto",
+			timestamp: int64(1568338208500),
+			expected:  rfc3339("2019-09-13T01:30:08.500Z"),
+		},
+		{
+			name:      "unix auto microseconds",
+			format:    "unix_auto",
+			timestamp: "1568338208000500",
+			expected:  rfc3339("2019-09-13T01:30:08.000500Z"),
+		},
+		{
+			name:      "unix auto nanoseconds",
+			format:    "unix_auto",
+			timestamp: "1568338208000000500",
+			expected:  rfc3339("2019-09-13T01:30:08.000000500Z"),
+		},
 		{
 			name:      "rfc339 test",
 			format:    "RFC3339",
@@ -559,6 +583,28 @@ func TestParseTimestamp(t *testing.T) {
 			expected:  rfc1123z("Mon, 02 Jan 2006 15:04:05 -0700"),
 		},
 
+		{
+			name:      "HTTP",
+			format:    "HTTP",
+			timestamp: "Sun, 06 Nov 1994 08:49:37 GMT",
+			expected:  rfc3339("1994-11-06T08:49:37Z"),
+		},
+
+		{
+			name:      "HTTP ignores the location",
+			format:    "HTTP",
+			timestamp: "Tue, 10 Sep 2019 01:30:08 GMT",
+			location:  "America/New_York",
+			expected:  rfc3339("2019-09-10T01:30:08Z"),
+		},
+
+		{
+			name:      "RFC2822",
+			format:    "RFC2822",
+			timestamp: "Fri, 21 Nov 1997 09:55:06 -0600",
+			expected:  rfc3339("1997-11-21T09:55:06-06:00"),
+		},
+
 		{
 			name:      "RFC3339Nano",
 			format:    "RFC3339Nano",
@@ -0,0 +1,293 @@
+package json_v2
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+)
+
+// cborMaxNesting limits the nesting of arrays, maps and tags of CBOR inputs, guarding the decoder against hostile
+// inputs before the regular depth check applies to the converted JSON
+const cborMaxNesting = 1000
+
+// cborToJSON converts a CBOR document (RFC 8949) to JSON, so it can be queried like any JSON input.
+// Byte strings become base64 encoded strings, bignums become numbers and other tags are ignored. Map keys that are
+// numbers, booleans or null are converted to strings, as JSON only supports string keys.
+func cborToJSON(input []byte) ([]byte, error) {
+	d := &cborDecoder{input: input}
+	var buf bytes.Buffer
+	if err := d.value(&buf, 0); err != nil {
+		return nil, err
+	}
+	if d.pos != len(d.input) {
+		return nil, fmt.Errorf("%d bytes after the end of the CBOR document", len(d.input)-d.pos)
+	}
+	return buf.Bytes(), nil
+}
+
+type cborDecoder struct {
+	input []byte
+	pos   int
+}
+
+// cborBreak marks the end of an indefinite length item
+const cborBreak = 0xff
+
+// head reads the major type and argument of the next item, indefinite is set for indefinite length items
+func (d *cborDecoder) head() (major byte, arg uint64, indefinite bool, err error) {
+	if d.pos >= len(d.input) {
+		return 0, 0, false, fmt.Errorf("unexpected end of the CBOR document at byte %d", d.pos)
+	}
+	initial := d.input[d.pos]
+	d.pos++
+	major, info := initial>>5, initial&0x1f
+
+	switch {
+	case info < 24:
+		return major, uint64(info), false, nil
+	case info <= 27:
+		n := 1 << (info - 24)
+		if len(d.input)-d.pos < n {
+			return 0, 0, false, fmt.Errorf("unexpected end of the CBOR document at byte %d", d.pos)
+		}
+		b := d.input[d.pos : d.pos+n]
+		d.pos += n
+		switch n {
+		case 1:
+			arg = uint64(b[0])
+		case 2:
+			arg = uint64(binary.BigEndian.Uint16(b))
+		case 4:
+			arg = uint64(binary.BigEndian.Uint32(b))
+		default:
+			arg = binary.BigEndian.Uint64(b)
+		}
+		return major, arg, false, nil
+	case info == 31 && major >= 2 && major != 6:
+		return major, 0, true, nil
+	}
+	return 0, 0, false, fmt.Errorf("invalid CBOR item 0x%02x at byte %d", initial, d.pos-1)
+}
+
+// atBreak consumes the break code ending an indefinite length item, if it is next
+func (d *cborDecoder) atBreak() bool {
+	if d.pos < len(d.input) && d.input[d.pos] == cborBreak {
+		d.pos++
+		return true
+	}
+	return false
+}
+
+// value converts the next item to JSON
+func (d *cborDecoder) value(buf *bytes.Buffer, depth int) error {
+	if depth > cborMaxNesting {
+		return fmt.Errorf("CBOR document nested deeper than %d levels", cborMaxNesting)
+	}
+
+	start := d.pos
+	major, arg, indefinite, err := d.head()
+	if err != nil {
+		return err
+	}
+
+	switch major {
+	case 0:
+		buf.WriteString(strconv.FormatUint(arg, 10))
+	case 1:
+		// The value is -1 - arg, which doesn't fit into an int64 for the largest arguments
+		n := new(big.Int).SetUint64(arg)
+		buf.WriteString(n.Neg(n.Add(n, big.NewInt(1))).String())
+	case 2:
+		b, err := d.bytes(major, arg, indefinite)
+		if err != nil {
+			return err
+		}
+		writeJSONString(buf, base64.StdEncoding.EncodeToString(b))
+	case 3:
+		b, err := d.bytes(major, arg, indefinite)
+		if err != nil {
+			return err
+		}
+		writeJSONString(buf, string(b))
+	case 4:
+		buf.WriteByte('[')
+		for i := uint64(0); indefinite || i < arg; i++ {
+			if indefinite && d.atBreak() {
+				break
+			}
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := d.value(buf, depth+1); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case 5:
+		buf.WriteByte('{')
+		for i := uint64(0); indefinite || i < arg; i++ {
+			if indefinite && d.atBreak() {
+				break
+			}
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := d.key(buf, depth+1); err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+			if err := d.value(buf, depth+1); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case 6:
+		return d.tagged(buf, arg, depth)
+	default:
+		return d.simple(buf, arg, start)
+	}
+	return nil
+}
+
+// bytes returns the content of a byte or text string, joining the chunks of indefinite length strings
+func (d *cborDecoder) bytes(major byte, length uint64, indefinite bool) ([]byte, error) {
+	if !indefinite {
+		if length > uint64(len(d.input)-d.pos) {
+			return nil, fmt.Errorf("unexpected end of the CBOR document at byte %d", d.pos)
+		}
+		b := d.input[d.pos : d.pos+int(length)]
+		d.pos += int(length)
+		return b, nil
+	}
+
+	var joined []byte
+	for !d.atBreak() {
+		start := d.pos
+		chunkMajor, chunkLength, chunkIndefinite, err := d.head()
+		if err != nil {
+			return nil, err
+		}
+		if chunkMajor != major || chunkIndefinite {
+			return nil, fmt.Errorf("invalid chunk of an indefinite length string at byte %d", start)
+		}
+		chunk, err := d.bytes(major, chunkLength, false)
+		if err != nil {
+			return nil, err
+		}
+		joined = append(joined, chunk...)
+	}
+	return joined, nil
+}
+
+// key converts the next item to a JSON object key
+func (d *cborDecoder) key(buf *bytes.Buffer, depth int) error {
+	start := d.pos
+	var key bytes.Buffer
+	if err := d.value(&key, depth); err != nil {
+		return err
+	}
+	switch b := key.Bytes(); {
+	case b[0] == '"':
+		buf.Write(b)
+	case b[0] == '[' || b[0] == '{':
+		return fmt.Errorf("unsupported CBOR map key at byte %d, must not be an array or map", start)
+	default:
+		writeJSONString(buf, string(b))
+	}
+	return nil
+}
+
+// tagged converts the content of a tag, bignums are converted to numbers while all other tags are ignored
+func (d *cborDecoder) tagged(buf *bytes.Buffer, tag uint64, depth int) error {
+	if tag != 2 && tag != 3 {
+		return d.value(buf, depth+1)
+	}
+
+	start := d.pos
+	major, length, indefinite, err := d.head()
+	if err != nil {
+		return err
+	}
+	if major != 2 {
+		return fmt.Errorf("invalid bignum at byte %d, must be a byte string", start)
+	}
+	b, err := d.bytes(major, length, indefinite)
+	if err != nil {
+		return err
+	}
+	n := new(big.Int).SetBytes(b)
+	if tag == 3 {
+		n.Neg(n.Add(n, big.NewInt(1)))
+	}
+	buf.WriteString(n.String())
+	return nil
+}
+
+// simple converts booleans, null, undefined and floats, numbers JSON can't represent like NaN become null
+func (d *cborDecoder) simple(buf *bytes.Buffer, arg uint64, start int) error {
+	var f float64
+	bitSize := 64
+	switch info := d.input[start] & 0x1f; {
+	case info == 20:
+		buf.WriteString("false")
+		return nil
+	case info == 21:
+		buf.WriteString("true")
+		return nil
+	case info == 22 || info == 23:
+		buf.WriteString("null")
+		return nil
+	case info == 25:
+		f = halfToFloat(uint16(arg))
+	case info == 26:
+		f = float64(math.Float32frombits(uint32(arg)))
+		bitSize = 32
+	case info == 27:
+		f = math.Float64frombits(arg)
+	case info == 31:
+		return fmt.Errorf("unexpected break code at byte %d", start)
+	default:
+		return fmt.Errorf("unsupported CBOR simple value %d at byte %d", arg, start)
+	}
+
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		buf.WriteString("null")
+		return nil
+	}
+	buf.WriteString(strconv.FormatFloat(f, 'g', -1, bitSize))
+	return nil
+}
+
+// halfToFloat converts an IEEE 754 half-precision float
+func halfToFloat(h uint16) float64 {
+	exp := int(h>>10) & 0x1f
+	mant := float64(h & 0x3ff)
+	var f float64
+	switch exp {
+	case 0:
+		f = math.Ldexp(mant, -24)
+	case 31:
+		if mant == 0 {
+			f = math.Inf(1)
+		} else {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(mant+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		return -f
+	}
+	return f
+}
+
+// writeJSONString writes the string quoted and escaped for JSON, invalid UTF-8 is replaced
+func writeJSONString(buf *bytes.Buffer, s string) {
+	b, _ := json.Marshal(s)
+	buf.Write(b)
+}
@@ -0,0 +1,77 @@
+package json_v2
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCBORToJSON(t *testing.T) {
+	// Examples taken from RFC 8949, Appendix A
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{input: "00", expected: "0"},
+		{input: "1903e8", expected: "1000"},
+		{input: "1bffffffffffffffff", expected: "18446744073709551615"},
+		{input: "3bffffffffffffffff", expected: "-18446744073709551616"},
+		{input: "c249010000000000000000", expected: "18446744073709551616"},
+		{input: "c349010000000000000000", expected: "-18446744073709551617"},
+		{input: "f93c00", expected: "1"},
+		{input: "f90001", expected: "5.960464477539063e-08"},
+		{input: "f9c400", expected: "-4"},
+		{input: "fa47c35000", expected: "100000"},
+		{input: "fa3dcccccd", expected: "0.1"},
+		{input: "fb3ff199999999999a", expected: "1.1"},
+		{input: "f97c00", expected: "null"},
+		{input: "fb7ff8000000000000", expected: "null"},
+		{input: "f4", expected: "false"},
+		{input: "f6", expected: "null"},
+		{input: "f7", expected: "null"},
+		{input: "4401020304", expected: `"AQIDBA=="`},
+		{input: "6449455446", expected: `"IETF"`},
+		{input: "62225c", expected: `"\"\\"`},
+		{input: "c074323031332d30332d32315432303a30343a30305a", expected: `"2013-03-21T20:04:00Z"`},
+		{input: "8301820203820405", expected: "[1,[2,3],[4,5]]"},
+		{input: "a201020304", expected: `{"1":2,"3":4}`},
+		{input: "a26161016162820203", expected: `{"a":1,"b":[2,3]}`},
+		{input: "7f657374726561646d696e67ff", expected: `"streaming"`},
+		{input: "9f018202039f0405ffff", expected: "[1,[2,3],[4,5]]"},
+		{input: "bf61610161629f0203ffff", expected: `{"a":1,"b":[2,3]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			input, err := hex.DecodeString(tt.input)
+			require.NoError(t, err)
+			actual, err := cborToJSON(input)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, string(actual))
+		})
+	}
+}
+
+func TestCBORToJSONErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "empty", input: "", expected: "unexpected end of the CBOR document at byte 0"},
+		{name: "truncated string", input: "644945", expected: "unexpected end of the CBOR document at byte 1"},
+		{name: "trailing bytes", input: "0101", expected: "1 bytes after the end of the CBOR document"},
+		{name: "reserved argument", input: "1c", expected: "invalid CBOR item 0x1c at byte 0"},
+		{name: "break outside of an item", input: "ff", expected: "unexpected break code at byte 0"},
+		{name: "array as map key", input: "a1800102", expected: "unsupported CBOR map key at byte 1, must not be an array or map"},
+		{name: "mixed string chunks", input: "7f4161ff", expected: "invalid chunk of an indefinite length string at byte 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input, err := hex.DecodeString(tt.input)
+			require.NoError(t, err)
+			_, err = cborToJSON(input)
+			require.EqualError(t, err, tt.expected)
+		})
+	}
+}
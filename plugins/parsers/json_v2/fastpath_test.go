@@ -0,0 +1,144 @@
+package json_v2
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSimpleConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		simple bool
+	}{
+		{
+			name: "plain keys",
+			config: Config{
+				Fields: []DataSet{{Path: "stats.cpu", Type: "float"}, {Path: "stats.mem", Rename: "memory"}},
+				Tags:   []DataSet{{Path: "host"}},
+			},
+			simple: true,
+		},
+		{
+			name: "empty value map of the configuration file",
+			config: Config{
+				Fields: []DataSet{{Path: "stats.cpu", ValueMap: map[string]string{}}},
+				Tags:   []DataSet{{Path: "host", ValueMap: map[string]string{}}},
+			},
+			simple: true,
+		},
+		{
+			name:   "optional",
+			config: Config{Fields: []DataSet{{Path: "stats.cpu", Optional: true}}},
+			simple: true,
+		},
+		{
+			name:   "wildcard",
+			config: Config{Fields: []DataSet{{Path: "stats.c*"}}},
+		},
+		{
+			name:   "array query",
+			config: Config{Fields: []DataSet{{Path: "servers.#.load"}}},
+		},
+		{
+			name:   "modifier",
+			config: Config{Fields: []DataSet{{Path: "@this"}}},
+		},
+		{
+			name:   "json type",
+			config: Config{Fields: []DataSet{{Path: "stats", Type: "json"}}},
+		},
+		{
+			name:   "value map",
+			config: Config{Fields: []DataSet{{Path: "state", ValueMap: map[string]string{"n/a": ""}}}},
+		},
+		{
+			name: "objects",
+			config: Config{
+				Fields:      []DataSet{{Path: "host"}},
+				JSONObjects: []JSONObject{{Path: "servers"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := &Parser{Configs: []Config{tt.config}, Log: testutil.Logger{}}
+			require.NoError(t, parser.Init())
+			require.Equal(t, tt.simple, parser.Configs[0].simple)
+		})
+	}
+}
+
+func TestSimpleConfigEquivalence(t *testing.T) {
+	inputs := []string{
+		`{"host":"server01","stats":{"cpu":0.5,"mem":"1024"}}`,
+		`{"host":"server01","stats":{"cpu":0.5}}`,
+		`{"host":"server01"}`,
+		`{"stats":{"cpu":[0.5,0.7],"mem":1024}}`,
+		`{"host":{"name":"server01"},"stats":{"cpu":0.5}}`,
+		`{"other":1}`,
+		`{}`,
+	}
+	for _, dropEmpty := range []bool{false, true} {
+		for _, input := range inputs {
+			t.Run(fmt.Sprintf("%s drop_empty_metrics=%t", input, dropEmpty), func(t *testing.T) {
+				fast := simpleParser(t, DataSet{Path: "host"})
+				expected := simpleParser(t, DataSet{Path: "host"})
+				require.True(t, fast.Configs[0].simple)
+				expected.Configs[0].simple = false
+				fast.Configs[0].DropEmptyMetrics = dropEmpty
+				expected.Configs[0].DropEmptyMetrics = dropEmpty
+
+				actual, err := fast.Parse([]byte(input))
+				require.NoError(t, err)
+				general, err := expected.Parse([]byte(input))
+				require.NoError(t, err)
+				testutil.RequireMetricsEqual(t, general, actual, testutil.IgnoreTime())
+			})
+		}
+	}
+}
+
+// BenchmarkSimpleConfig compares the direct lookup of plain fields with the general processing of the same fields
+func BenchmarkSimpleConfig(b *testing.B) {
+	input := []byte(`{"host":"server01","time":1609459200,"stats":{"cpu":0.5,"mem":1024,"disk":{"used":12,"free":88}}}`)
+	for _, simple := range []bool{true, false} {
+		name := "general"
+		if simple {
+			name = "fast"
+		}
+		b.Run(name, func(b *testing.B) {
+			parser := simpleParser(b)
+			require.True(b, parser.Configs[0].simple)
+			parser.Configs[0].simple = simple
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, err := parser.Parse(input)
+				require.NoError(b, err)
+			}
+		})
+	}
+}
+
+func simpleParser(tb testing.TB, tags ...DataSet) *Parser {
+	parser := &Parser{
+		Configs: []Config{
+			{
+				MeasurementName: "stats",
+				Fields: []DataSet{
+					{Path: "stats.cpu", Type: "float"},
+					{Path: "stats.mem", Rename: "memory", Type: "int"},
+				},
+				Tags: tags,
+			},
+		},
+		Log: testutil.Logger{},
+	}
+	require.NoError(tb, parser.Init())
+	return parser
+}
@@ -1,51 +1,605 @@
 package json_v2
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"hash"
+	"hash/fnv"
+	"io"
+	"io/ioutil"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/cespare/xxhash/v2"
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/parsers/logfmt"
+	"github.com/influxdata/wlog"
 	"github.com/tidwall/gjson"
+	"github.com/tinylib/msgp/msgp"
+	"golang.org/x/text/unicode/norm"
 )
 
+// utf8BOM is the byte order mark some tools, mostly on Windows, write at the start of UTF-8 files
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+// ErrEmptyInput is returned by ParseLine for an empty or whitespace-only line, such as a blank line of a file
+var ErrEmptyInput = errors.New("empty input")
+
 type Parser struct {
-	Configs     []Config
-	DefaultTags map[string]string
-	Log         telegraf.Logger
-	Timestamp   time.Time
+	Configs            []Config
+	DefaultTags        map[string]string
+	Log                telegraf.Logger
+	Timestamp          time.Time
+	AllFieldsAsStrings bool
+	Strict             bool
+	// StrictObjects fails the parsing if a 'field' or 'tag' path matches an object instead of skipping it, the
+	// configuration sets it only if 'json_v2_strict' is set explicitly, as strict mode is its default
+	StrictObjects bool
+	// Dedup keeps only the last metric of every series within the metrics returned by a single call
+	Dedup bool
+	// TrimSpace removes leading and trailing whitespace from tag keys and values, field keys and string field values
+	TrimSpace bool
+	// InvalidUTF8 is either "replace" to replace invalid UTF-8 in tags and string fields by the Unicode replacement
+	// character or "drop" to remove it, by default the strings are kept as they are
+	InvalidUTF8 string
+	// UnicodeNormalization is the normalization form like "NFC" applied to tags and string fields, if set
+	UnicodeNormalization string
+	// OnError is either "fail" to fail the whole input on an error or "skip" to skip the failing configurations or lines
+	// but keep all other metrics, the metrics are then returned along with an error summarizing the skipped parts
+	OnError string
+	// ForceFloat makes all numeric fields floats, unless the field explicitly has the type int or uint
+	ForceFloat bool
+	// TypeInference keeps integer numbers of fields without a type as integers instead of converting them to floats
+	TypeInference bool
+	// TimePrecision truncates the time of the metrics to a multiple of the duration if set, e.g. to full seconds
+	TimePrecision time.Duration
+	// TimeFunc is used for the timestamp of configurations not defining a timestamp_path, defaults to time.Now
+	TimeFunc func() time.Time
+	// MaxLineSize is the maximum size of a line read by ParseReader in bytes, defaults to bufio.MaxScanTokenSize
+	MaxLineSize int
+	// OnMissing is either "skip" (default) to skip fields and tags whose path doesn't match a value, "error" to fail
+	// like for invalid values, or "default" to require a default_value for all of them. Optional ones are always
+	// skipped, while a default_value is used in all modes.
+	OnMissing string
+	// JSONLines makes Parse handle the input as newline-delimited JSON, parsing every line like ParseReader does
+	JSONLines bool
+	// ParseErrorsMeasurement is the name of a metric counting inputs that are no valid JSON, instead of failing
+	ParseErrorsMeasurement string
+	// ParseErrorsSnippetLength is the number of characters of the first invalid input added as 'snippet' tag
+	ParseErrorsSnippetLength int
+	// MaxBodySize is the maximum size of an input in bytes, larger inputs are rejected before decoding them
+	MaxBodySize int
+	// StripPrefixRegex is a regular expression matching a prefix removed from the start of every input before decoding
+	// it, like the header of a log line. Inputs not matching are decoded as they are
+	StripPrefixRegex string
+	// MaxDepth is the maximum nesting of objects and arrays in an input, deeper inputs are rejected before decoding them
+	MaxDepth int
+	// Format is the encoding of the inputs, either "json" (default), "msgpack" or "cbor". Binary inputs are converted
+	// to JSON before querying them, so the same configurations apply regardless of the encoding
+	Format string
+	// MaxMetrics is the maximum number of metrics returned by a single call, after filtering, sampling and dedup
+	MaxMetrics int
+	// MaxMetricsMode is either "fail" to fail a call exceeding MaxMetrics or "truncate" to only return the first
+	// MaxMetrics metrics, defaults to "fail"
+	MaxMetricsMode string
 
 	measurementName string
+	indexTag        string
+	sampleEveryN    int
+	tagPrecedence   string
+	line            int
+	deltas          *deltaState
+	document        []byte
+	stripPrefix     *regexp.Regexp
+	queries         *queryCache
 
 	iterateObjects  bool
 	currentSettings JSONObject
 }
 
 type Config struct {
-	MeasurementName     string `toml:"measurement_name"`      // OPTIONAL
-	MeasurementNamePath string `toml:"measurement_name_path"` // OPTIONAL
-	TimestampPath       string `toml:"timestamp_path"`        // OPTIONAL
-	TimestampFormat     string `toml:"timestamp_format"`      // OPTIONAL, but REQUIRED when timestamp_path is defined
-	TimestampTimezone   string `toml:"timestamp_timezone"`    // OPTIONAL, but REQUIRES timestamp_path
+	MeasurementName     string            `toml:"measurement_name"`      // OPTIONAL
+	MeasurementNamePath string            `toml:"measurement_name_path"` // OPTIONAL
+	TimestampPath       string            `toml:"timestamp_path"`        // OPTIONAL
+	TimestampFormat     string            `toml:"timestamp_format"`      // OPTIONAL, but REQUIRED when timestamp_path is defined
+	TimestampFormats    []string          `toml:"timestamp_formats"`     // OPTIONAL, tried in order after timestamp_format
+	TimestampTimezone   string            `toml:"timestamp_timezone"`    // OPTIONAL, but REQUIRES timestamp_path
+	TimestampDefault    string            `toml:"timestamp_default"`     // OPTIONAL, but REQUIRES timestamp_format
+	TotalCountField     string            `toml:"total_count_field"`     // OPTIONAL
+	Unwrap              string            `toml:"unwrap"`                // OPTIONAL
+	EmitMatchCoverage   bool              `toml:"emit_match_coverage"`   // OPTIONAL
+	ExpiryTag           string            `toml:"expiry_tag"`            // OPTIONAL
+	ExpiryAfter         time.Duration     `toml:"expiry_after"`          // OPTIONAL, but REQUIRED when expiry_tag is defined
+	DropEmptyMetrics    bool              `toml:"drop_empty_metrics"`    // OPTIONAL, defaults to true in the configuration file
+	IndexTag            string            `toml:"index_tag"`             // OPTIONAL
+	DefaultFieldName    string            `toml:"default_field_name"`    // OPTIONAL, defaults to "value"
+	DefaultTags         map[string]string `toml:"default_tags"`          // OPTIONAL
+	RootPath            string            `toml:"root_path"`             // OPTIONAL
+	SampleEveryN        int               `toml:"sample_every_n"`        // OPTIONAL
+	EmitCount           bool              `toml:"emit_count"`            // OPTIONAL
+	CountMeasurement    string            `toml:"count_measurement"`     // OPTIONAL, defaults to the measurement name with a "_count" suffix
+	HashTag             string            `toml:"hash_tag"`              // OPTIONAL
+	HashKeys            []string          `toml:"hash_keys"`             // OPTIONAL, but REQUIRED when hash_tag is defined
+	HashAlgorithm       string            `toml:"hash_algorithm"`        // OPTIONAL, can be "fnv" or "xxhash", defaults to "fnv"
+	TagPrecedence       string            `toml:"tag_precedence"`        // OPTIONAL, can be "first" or "last", defaults to "last"
 
 	Fields      []DataSet
 	Tags        []DataSet
 	JSONObjects []JSONObject
+	Filters     []Filter
+	Lookups     []Lookup
+	Groups      []Group
+
+	// defaultTime is the parsed TimestampDefault, set by Init or on parsing
+	defaultTime time.Time
+	// simple is set by Init if all fields and tags are plain keys, allowing to add them without expanding arrays
+	simple bool
+}
+
+// Group gathers fields and tags relative to a sub-document, adding them to the metrics of the configuration with
+// a prefix, so values spread over several sub-documents end up in the same metric
+type Group struct {
+	RootPath string `toml:"root_path"` // REQUIRED
+	Prefix   string `toml:"prefix"`    // OPTIONAL
+
+	Fields []DataSet
+	Tags   []DataSet
+}
+
+// dataSetList holds the 'field' or the 'tag' configs of a configuration or a group
+type dataSetList struct {
+	kind string
+	sets []DataSet
+}
+
+// dataSets returns the 'field' and 'tag' configs of the configuration and its groups
+func (c *Config) dataSets() []dataSetList {
+	sets := []dataSetList{{"field", c.Fields}, {"tag", c.Tags}}
+	for _, g := range c.Groups {
+		sets = append(sets, dataSetList{"field", g.Fields}, dataSetList{"tag", g.Tags})
+	}
+	return sets
+}
+
+// validatePaths checks the syntax of all paths of the configuration, naming the option holding a malformed path
+func (c *Config) validatePaths() error {
+	for _, option := range []struct{ name, path string }{
+		{"measurement_name_path", c.MeasurementNamePath},
+		{"timestamp_path", c.TimestampPath},
+		{"root_path", c.RootPath},
+		{"unwrap", c.Unwrap},
+	} {
+		if option.path == "" {
+			continue
+		}
+		if err := validatePath(option.path); err != nil {
+			return fmt.Errorf("%s: invalid path '%s': %v", option.name, option.path, err)
+		}
+	}
+
+	for _, placeholder := range measurementNamePlaceholder.FindAllStringSubmatch(c.MeasurementName, -1) {
+		if err := validatePath(placeholder[1]); err != nil {
+			return fmt.Errorf("measurement_name: invalid path '%s': %v", placeholder[1], err)
+		}
+	}
+
+	for _, f := range c.Filters {
+		if err := validatePath(f.Path); err != nil {
+			return fmt.Errorf("filter: invalid path '%s': %v", f.Path, err)
+		}
+	}
+
+	for _, o := range c.JSONObjects {
+		if err := validatePath(o.Path); err != nil {
+			return fmt.Errorf("object: invalid path '%s': %v", o.Path, err)
+		}
+	}
+
+	for _, g := range c.Groups {
+		if err := validatePath(g.RootPath); err != nil {
+			return fmt.Errorf("group: invalid root_path '%s': %v", g.RootPath, err)
+		}
+	}
+
+	for _, sets := range c.dataSets() {
+		kind := sets.kind
+		for _, d := range sets.sets {
+			if d.Value != "" {
+				if d.Path != "" || d.Rename == "" {
+					return fmt.Errorf("%s with value '%s' requires rename and must not have a path", kind, d.Value)
+				}
+				continue
+			}
+			for _, path := range append([]string{d.Path}, d.FallbackPaths...) {
+				parts := []string{path}
+				if d.ParseNestedJSON {
+					parts = strings.Split(path, nestedSeparator)
+				}
+				for _, part := range parts {
+					if err := validatePath(part); err != nil {
+						return fmt.Errorf("%s '%s': invalid path '%s': %v", kind, d.name(), path, err)
+					}
+				}
+			}
+			for _, f := range d.Conditions {
+				if err := validatePath(f.Path); err != nil {
+					return fmt.Errorf("%s '%s': invalid condition path '%s': %v", kind, d.name(), f.Path, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// validatePath checks the syntax of a GJSON path, as GJSON silently matches nothing for a malformed path
+// This catches typos like unbalanced brackets, empty keys and unknown modifiers
+func validatePath(path string) error {
+	path = strings.TrimPrefix(path, recursiveDescentPrefix)
+	if path == "" {
+		return errors.New("path is empty")
+	}
+	path, err := convertJSONPath(path)
+	if err != nil {
+		return err
+	}
+
+	var closing []byte
+	var inString, escaped bool
+	keyStart := true
+	for i := 0; i < len(path); i++ {
+		b := path[i]
+		switch {
+		case escaped:
+			escaped = false
+		case b == '\\':
+			escaped = true
+		case inString:
+			if b == '"' {
+				inString = false
+			}
+		case b == '"' && len(closing) > 0:
+			inString = true
+		case b == '(':
+			closing = append(closing, ')')
+		case b == '[':
+			closing = append(closing, ']')
+		case b == '{':
+			closing = append(closing, '}')
+		case b == ')' || b == ']' || b == '}':
+			if len(closing) == 0 || closing[len(closing)-1] != b {
+				return fmt.Errorf("unexpected '%c' at position %d", b, i+1)
+			}
+			closing = closing[:len(closing)-1]
+		case len(closing) == 0 && (b == '.' || b == '|'):
+			if keyStart {
+				return fmt.Errorf("empty key at position %d", i+1)
+			}
+			keyStart = true
+			continue
+		case len(closing) == 0 && keyStart && b == '@':
+			name := path[i+1:]
+			if end := strings.IndexAny(name, ".|:"); end >= 0 {
+				name = name[:end]
+			}
+			if !gjson.ModifierExists(name, nil) {
+				return fmt.Errorf("unknown modifier '@%s'", name)
+			}
+		}
+		keyStart = false
+	}
+
+	switch {
+	case escaped:
+		return errors.New("path ends with an escape character")
+	case inString:
+		return errors.New("missing closing '\"'")
+	case len(closing) > 0:
+		return fmt.Errorf("missing closing '%c'", closing[len(closing)-1])
+	case keyStart:
+		return errors.New("path ends with a separator")
+	}
+	return nil
+}
+
+// fields returns the 'field' configs, naming fields without a name of their own after the default field name
+// This is the case for paths that select the value itself rather than a key, like '@this' for a scalar document
+func (c *Config) fields() []DataSet {
+	name := c.DefaultFieldName
+	if name == "" {
+		name = "value"
+	}
+
+	fields := make([]DataSet, len(c.Fields))
+	for i, f := range c.Fields {
+		if n := f.name(); n == "" || strings.HasPrefix(n, "@") || (f.pivots() && f.Rename == "") {
+			f.Rename = name
+		}
+		fields[i] = f
+	}
+	return fields
 }
 
 type DataSet struct {
-	Path   string `toml:"path"`   // REQUIRED
-	Type   string `toml:"type"`   // OPTIONAL, can't be set for tags they will always be a string
-	Rename string `toml:"rename"` // OPTIONAL
+	Path              string            `toml:"path"`                 // REQUIRED
+	FallbackPaths     []string          `toml:"fallback_paths"`       // OPTIONAL
+	Type              string            `toml:"type"`                 // OPTIONAL, tags are converted to the type and then formatted as string
+	Rename            string            `toml:"rename"`               // OPTIONAL
+	RequireMonotonic  bool              `toml:"require_monotonic"`    // OPTIONAL
+	Flatten           bool              `toml:"flatten"`              // OPTIONAL
+	FlattenSeparator  string            `toml:"flatten_separator"`    // OPTIONAL, defaults to "_"
+	TrueValues        []string          `toml:"true_values"`          // OPTIONAL
+	FalseValues       []string          `toml:"false_values"`         // OPTIONAL
+	Bitfields         map[int]string    `toml:"bitfields"`            // OPTIONAL
+	DecimalSeparator  string            `toml:"decimal_separator"`    // OPTIONAL
+	GroupSeparator    string            `toml:"group_separator"`      // OPTIONAL
+	ParseNestedJSON   bool              `toml:"parse_nested_json"`    // OPTIONAL
+	Scale             float64           `toml:"scale"`                // OPTIONAL, defaults to 1
+	Offset            float64           `toml:"offset"`               // OPTIONAL
+	JoinArray         bool              `toml:"join_array"`           // OPTIONAL
+	JoinSeparator     string            `toml:"join_separator"`       // OPTIONAL, defaults to ","
+	MaxMatches        int               `toml:"max_matches"`          // OPTIONAL
+	IncludeKeys       []string          `toml:"include_keys"`         // OPTIONAL
+	ExcludeKeys       []string          `toml:"exclude_keys"`         // OPTIONAL
+	StrictBool        bool              `toml:"strict_bool"`          // OPTIONAL
+	SubFormat         string            `toml:"sub_format"`           // OPTIONAL
+	Required          bool              `toml:"required"`             // OPTIONAL, only for tags
+	Optional          bool              `toml:"optional"`             // OPTIONAL, exempts the value from json_v2_on_missing
+	Overflow          string            `toml:"overflow"`             // OPTIONAL, defaults to "skip"
+	KeyTag            string            `toml:"key_tag"`              // OPTIONAL, only for fields
+	KeySplit          string            `toml:"key_split"`            // OPTIONAL, only for fields
+	KeySplitTags      []string          `toml:"key_split_tags"`       // OPTIONAL, only for fields
+	SourcePathTag     string            `toml:"source_path_tag"`      // OPTIONAL
+	Conditions        []Filter          `toml:"condition"`            // OPTIONAL
+	Split             string            `toml:"split"`                // OPTIONAL
+	SplitNames        []string          `toml:"split_names"`          // OPTIONAL, required if split is set
+	SplitTypes        []string          `toml:"split_types"`          // OPTIONAL, defaults to type
+	EmptyStringAsNull bool              `toml:"empty_string_as_null"` // OPTIONAL
+	DefaultValue      string            `toml:"default_value"`        // OPTIONAL
+	ValueMap          map[string]string `toml:"value_map"`            // OPTIONAL
+	TrimSpace         bool              `toml:"trim_space"`           // OPTIONAL
+	Lowercase         bool              `toml:"lowercase"`            // OPTIONAL
+	KeepRaw           bool              `toml:"keep_raw"`             // OPTIONAL, only for fields
+	RawSuffix         string            `toml:"raw_suffix"`           // OPTIONAL, defaults to "_raw"
+	Columnar          bool              `toml:"columnar"`             // OPTIONAL, only for fields
+	Delta             bool              `toml:"delta"`                // OPTIONAL, only for fields
+	DeltaReset        string            `toml:"delta_reset"`          // OPTIONAL, can be "value" or "zero", defaults to "value"
+	Aggregate         string            `toml:"aggregate"`            // OPTIONAL, can be "sum", "avg", "min", "max" or "count"
+	Value             string            `toml:"value"`                // OPTIONAL, used instead of path
+	EnumMap           map[string]int64  `toml:"enum_map"`             // OPTIONAL
+	// SubParser parses string values holding another format, it is created from SubFormat if not set
+	SubParser SubParser `toml:"-"`
+
+	keyFilter filter.Filter
+}
+
+// SubParser parses a string value holding another format, like key=value pairs, into metrics
+// The fields and tags of the resulting metrics are added to the metric of the 'field' or 'tag'
+type SubParser interface {
+	Parse(buf []byte) ([]telegraf.Metric, error)
+}
+
+// subParser returns the parser for string values holding another format, or nil if not configured
+func (d *DataSet) subParser() (SubParser, error) {
+	if d.SubParser != nil {
+		return d.SubParser, nil
+	}
+	switch d.SubFormat {
+	case "":
+		return nil, nil
+	case "logfmt":
+		return logfmt.NewParser("", nil), nil
+	}
+	return nil, fmt.Errorf("invalid sub_format '%s' of '%s', must be 'logfmt'", d.SubFormat, d.Path)
+}
+
+// compileKeyFilter compiles the glob patterns of the keys to include or exclude when flattening
+func (d *DataSet) compileKeyFilter() error {
+	if len(d.IncludeKeys) == 0 && len(d.ExcludeKeys) == 0 {
+		return nil
+	}
+	f, err := filter.NewIncludeExcludeFilter(d.IncludeKeys, d.ExcludeKeys)
+	if err != nil {
+		return fmt.Errorf("invalid include_keys or exclude_keys of '%s': %w", d.Path, err)
+	}
+	d.keyFilter = f
+	return nil
+}
+
+// simplePath matches paths consisting of plain keys only, like "stats.cpu"
+var simplePath = regexp.MustCompile(`^[A-Za-z_][\w-]*(\.[A-Za-z_][\w-]*)*$`)
+
+// simple returns true if the data set only sets a plain path, a name, a scalar type and whether it is optional
+func (d *DataSet) simple() bool {
+	switch d.Type {
+	case "", "int", "uint", "float", "string", "bool":
+	default:
+		return false
+	}
+	// Loading the configuration file sets an empty value map, which is the same as no value map
+	plain := *d
+	if len(plain.ValueMap) == 0 {
+		plain.ValueMap = nil
+	}
+	return simplePath.MatchString(d.Path) && reflect.DeepEqual(plain, DataSet{Path: d.Path, Rename: d.Rename, Type: d.Type, Optional: d.Optional})
+}
+
+// isSimple returns true if the configuration only consists of simple fields and tags
+func (c *Config) isSimple() bool {
+	if len(c.JSONObjects) != 0 || len(c.Groups) != 0 || len(c.Fields)+len(c.Tags) == 0 {
+		return false
+	}
+	for _, sets := range [][]DataSet{c.Fields, c.Tags} {
+		for i := range sets {
+			if !sets[i].simple() {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// nestedSeparator separates the parts of a path that are applied to a string value holding a JSON document
+const nestedSeparator = "->"
+
+// name returns the name of the resulting field or tag
+func (d *DataSet) name() string {
+	name := d.Rename
+	// Default to the last path word, should be the upper key name
+	if name == "" {
+		path := d.Path
+		if i := strings.LastIndex(path, nestedSeparator); d.ParseNestedJSON && i >= 0 {
+			path = path[i+len(nestedSeparator):]
+		}
+		name = path[strings.LastIndex(path, ".")+1:]
+	}
+	return strings.ReplaceAll(name, " ", "_")
+}
+
+// query returns the result of the path, or of the first fallback path if the path doesn't match a non-null value
+func (d *DataSet) query(input []byte) (gjson.Result, error) {
+	result, err := d.queryPath(input, d.Path)
+	for _, path := range d.FallbackPaths {
+		if err != nil || (result.Exists() && result.Type != gjson.Null) {
+			break
+		}
+		result, err = d.queryPath(input, path)
+	}
+	return result, err
+}
+
+// queryKey identifies the query of the data set for caching its result, it is the path itself for plain paths so
+// the result is shared with other options using the same path
+func (d *DataSet) queryKey() string {
+	if !d.ParseNestedJSON && len(d.FallbackPaths) == 0 {
+		return d.Path
+	}
+	return fmt.Sprintf("%t\x00%s\x00%s", d.ParseNestedJSON, d.Path, strings.Join(d.FallbackPaths, "\x00"))
+}
+
+// matchedPath returns the path or fallback path resulting in the value returned by query
+func (d *DataSet) matchedPath(input []byte) string {
+	for _, path := range append([]string{d.Path}, d.FallbackPaths...) {
+		result, err := d.queryPath(input, path)
+		if err != nil {
+			break
+		}
+		if result.Exists() && result.Type != gjson.Null {
+			if d.ParseNestedJSON || strings.HasPrefix(path, recursiveDescentPrefix) {
+				return path
+			}
+			return resolvePath(input, path)
+		}
+	}
+	return d.Path
+}
+
+// queryPath returns the result of a single path
+// With parse_nested_json set, a path like "meta->region" gets the string at "meta", decodes it as a JSON document
+// and applies "region" to the decoded document
+func (d *DataSet) queryPath(input []byte, path string) (gjson.Result, error) {
+	if !d.ParseNestedJSON {
+		return getPath(input, path), nil
+	}
+
+	parts := strings.Split(path, nestedSeparator)
+	result := getPath(input, parts[0])
+	for i, part := range parts[1:] {
+		if !result.Exists() {
+			return result, nil
+		}
+		if result.Type != gjson.String {
+			return gjson.Result{}, fmt.Errorf("value at '%s' is not a string holding JSON", strings.Join(parts[:i+1], nestedSeparator))
+		}
+		inner := result.String()
+		if !gjson.Valid(inner) {
+			return gjson.Result{}, fmt.Errorf("value at '%s' holds invalid JSON", strings.Join(parts[:i+1], nestedSeparator))
+		}
+		result = getPath([]byte(inner), part)
+	}
+	return result, nil
+}
+
+type Filter struct {
+	Path     string `toml:"path"`     // REQUIRED
+	Operator string `toml:"operator"` // OPTIONAL, can be "==", "!=", ">", ">=", "<" or "<=", defaults to "=="
+	Value    string `toml:"value"`    // REQUIRED
+}
+
+type Lookup struct {
+	Tag   string                       `toml:"tag"`   // REQUIRED
+	File  string                       `toml:"file"`  // OPTIONAL
+	Table map[string]map[string]string `toml:"table"` // OPTIONAL
+
+	// loaded is set once the file was added to the table, by Init or on the first parse
+	loaded bool
+}
+
+// load will add the entries of the lookup file to the table, tags defined in the table take precedence
+// The file must contain a JSON object mapping each key to an object with the tags to add
+func (l *Lookup) load() error {
+	if l.Tag == "" {
+		return fmt.Errorf("tag is required for lookup")
+	}
+	if l.File == "" || l.loaded {
+		return nil
+	}
+
+	buf, err := ioutil.ReadFile(l.File)
+	if err != nil {
+		return fmt.Errorf("unable to read lookup file: %w", err)
+	}
+	var table map[string]map[string]string
+	if err := json.Unmarshal(buf, &table); err != nil {
+		return fmt.Errorf("unable to parse lookup file '%s': %w", l.File, err)
+	}
+
+	if l.Table == nil {
+		l.Table = make(map[string]map[string]string, len(table))
+	}
+	for key, tags := range table {
+		if _, ok := l.Table[key]; !ok {
+			l.Table[key] = make(map[string]string, len(tags))
+		}
+		for k, v := range tags {
+			if _, ok := l.Table[key][k]; !ok {
+				l.Table[key][k] = v
+			}
+		}
+	}
+	l.loaded = true
+	return nil
+}
+
+// apply will add the tags found in the table for the value of the lookup tag, existing tags are kept
+func (l *Lookup) apply(m telegraf.Metric) {
+	key, ok := m.GetTag(l.Tag)
+	if !ok {
+		return
+	}
+	for k, v := range l.Table[key] {
+		if !m.HasTag(k) {
+			m.AddTag(k, v)
+		}
+	}
 }
 
 type JSONObject struct {
 	Path               string            `toml:"path"`                 // REQUIRED
 	TimestampKey       string            `toml:"timestamp_key"`        // OPTIONAL
 	TimestampFormat    string            `toml:"timestamp_format"`     // OPTIONAL, but REQUIRED when timestamp_path is defined
+	TimestampFormats   []string          `toml:"timestamp_formats"`    // OPTIONAL, tried in order after timestamp_format
 	TimestampTimezone  string            `toml:"timestamp_timezone"`   // OPTIONAL, but REQUIRES timestamp_path
 	Renames            map[string]string `toml:"renames"`              // OPTIONAL
 	Fields             map[string]string `toml:"fields"`               // OPTIONAL
@@ -59,146 +613,2340 @@ type MetricNode struct {
 	OutputName  string
 	SetName     string
 	Tag         bool
-	DesiredType string // Can be "int", "uint", "float", "bool", "string"
+	DesiredType string  // Can be "int", "uint", "float", "bool", "string"
+	Settings    DataSet // The 'field' or 'tag' config the node originates from, empty for objects
 
 	Metric telegraf.Metric
 	gjson.Result
+
+	sourcePath string // The concrete path of the result within the document, only set if tagging the source path
 }
 
-func (p *Parser) Parse(input []byte) ([]telegraf.Metric, error) {
-	// Only valid JSON is supported
-	if !gjson.Valid(string(input)) {
-		return nil, fmt.Errorf("Invalid JSON provided, unable to parse")
+// Init will check the configuration for errors
+// The names of the fields in a configuration must be unique, as later fields would overwrite earlier ones otherwise
+func (p *Parser) Init() error {
+	switch p.OnError {
+	case "", "fail", "skip":
+	default:
+		return fmt.Errorf("invalid on_error mode '%s', must be 'fail' or 'skip'", p.OnError)
 	}
 
-	var metrics []telegraf.Metric
+	if err := p.compileStripPrefix(); err != nil {
+		return err
+	}
 
-	for _, c := range p.Configs {
-		// Measurement name configuration
-		p.measurementName = c.MeasurementName
-		if c.MeasurementNamePath != "" {
-			result := gjson.GetBytes(input, c.MeasurementNamePath)
-			if !result.IsArray() && !result.IsObject() {
-				p.measurementName = result.String()
-			}
+	switch p.MaxMetricsMode {
+	case "", "fail", "truncate":
+	default:
+		return fmt.Errorf("invalid max_metrics_mode '%s', must be 'fail' or 'truncate'", p.MaxMetricsMode)
+	}
+
+	switch p.Format {
+	case "", "json", "msgpack", "cbor":
+	default:
+		return fmt.Errorf("invalid format '%s', must be 'json', 'msgpack' or 'cbor'", p.Format)
+	}
+	switch p.OnMissing {
+	case "", "skip", "error", "default":
+	default:
+		return fmt.Errorf("invalid on_missing mode '%s', must be 'skip', 'error' or 'default'", p.OnMissing)
+	}
+
+	if p.JSONLines && p.Format != "" && p.Format != "json" {
+		return fmt.Errorf("json_lines requires the format 'json', not '%s'", p.Format)
+	}
+
+	switch p.InvalidUTF8 {
+	case "", "replace", "drop":
+	default:
+		return fmt.Errorf("invalid invalid_utf8 mode '%s', must be 'replace' or 'drop'", p.InvalidUTF8)
+	}
+	if _, ok := normalizationForms[strings.ToUpper(p.UnicodeNormalization)]; !ok && p.UnicodeNormalization != "" {
+		return fmt.Errorf("invalid unicode_normalization '%s', must be 'NFC', 'NFD', 'NFKC' or 'NFKD'", p.UnicodeNormalization)
+	}
+
+	p.initDeltas()
+	if err := p.loadLookups(); err != nil {
+		return err
+	}
+	for i := range p.Configs {
+		c := &p.Configs[i]
+		if err := c.validatePaths(); err != nil {
+			return fmt.Errorf("json_v2[%d] (measurement '%s'), %v", i, c.MeasurementName, err)
 		}
 
-		// Timestamp configuration
-		p.Timestamp = time.Now()
-		if c.TimestampPath != "" {
-			result := gjson.GetBytes(input, c.TimestampPath)
-			if !result.IsArray() && !result.IsObject() {
-				if c.TimestampFormat == "" {
-					err := fmt.Errorf("use of 'timestamp_query' requires 'timestamp_format'")
-					return nil, err
+		for _, list := range c.dataSets() {
+			sets := list.sets
+			for j := range sets {
+				if err := sets[j].compileKeyFilter(); err != nil {
+					return err
 				}
-
-				var err error
-				p.Timestamp, err = internal.ParseTimestamp(c.TimestampFormat, result.Value(), c.TimestampTimezone)
+				if (len(sets[j].KeySplitTags) != 0) != (sets[j].KeySplit != "") {
+					return fmt.Errorf("key_split and key_split_tags of '%s' must be set together", sets[j].Path)
+				}
+				if sets[j].Split != "" && len(sets[j].SplitNames) == 0 {
+					return fmt.Errorf("split of '%s' requires split_names", sets[j].Path)
+				}
+				switch sets[j].Aggregate {
+				case "", "sum", "avg", "min", "max", "count":
+				default:
+					return fmt.Errorf("invalid aggregate '%s' of '%s', must be 'sum', 'avg', 'min', 'max' or 'count'", sets[j].Aggregate, sets[j].Path)
+				}
+				switch sets[j].DeltaReset {
+				case "", "value", "zero":
+				default:
+					return fmt.Errorf("invalid delta_reset '%s' of '%s', must be 'value' or 'zero'", sets[j].DeltaReset, sets[j].Path)
+				}
+				switch sets[j].Overflow {
+				case "", "skip", "clamp":
+				default:
+					return fmt.Errorf("invalid overflow mode '%s' of '%s', must be 'skip' or 'clamp'", sets[j].Overflow, sets[j].Path)
+				}
+				if p.OnMissing == "default" && !sets[j].Optional && !sets[j].Required && sets[j].DefaultValue == "" && sets[j].Value == "" {
+					return fmt.Errorf("%s '%s' requires a default_value or optional with on_missing 'default'", list.kind, sets[j].name())
+				}
+				sp, err := sets[j].subParser()
 				if err != nil {
-					return nil, err
+					return err
 				}
+				sets[j].SubParser = sp
 			}
 		}
 
-		fields, err := p.processMetric(c.Fields, input, false)
-		if err != nil {
-			return nil, err
+		if (c.HashTag != "") != (len(c.HashKeys) != 0) {
+			return fmt.Errorf("hash_tag and hash_keys of measurement '%s' must be set together", c.MeasurementName)
+		}
+		switch c.HashAlgorithm {
+		case "", "fnv", "xxhash":
+		default:
+			return fmt.Errorf("invalid hash_algorithm '%s' of measurement '%s', must be 'fnv' or 'xxhash'", c.HashAlgorithm, c.MeasurementName)
+		}
+
+		if err := p.parseTimestampDefault(c); err != nil {
+			return err
+		}
+
+		switch c.TagPrecedence {
+		case "", "first", "last":
+		default:
+			return fmt.Errorf("invalid tag_precedence '%s' of measurement '%s', must be 'first' or 'last'", c.TagPrecedence, c.MeasurementName)
+		}
+
+		c.simple = c.isSimple()
+
+		names := make(map[string]bool, len(c.Fields))
+		for _, f := range c.fields() {
+			name := f.name()
+			if names[name] {
+				return fmt.Errorf("duplicate field name '%s' in configuration for measurement '%s'", name, c.MeasurementName)
+			}
+			names[name] = true
+		}
+	}
+
+	return nil
+}
+
+// initDeltas creates the state shared by all parse calls if a field computes deltas, also for parsers used without
+// Init like the ones created for every file of the tail input
+func (p *Parser) initDeltas() {
+	if p.deltas != nil {
+		return
+	}
+	for i := range p.Configs {
+		for _, list := range p.Configs[i].dataSets() {
+			for _, d := range list.sets {
+				if d.Delta {
+					p.deltas = &deltaState{last: make(map[deltaKey]interface{})}
+					return
+				}
+			}
+		}
+	}
+}
+
+// loadLookups adds the files of all lookups to their tables, also for parsers used without Init
+func (p *Parser) loadLookups() error {
+	for i := range p.Configs {
+		for j := range p.Configs[i].Lookups {
+			if err := p.Configs[i].Lookups[j].load(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Parse is safe for concurrent use once the parser is initialized, as long as the exported fields are not modified
+// The state of a single call is kept in a copy of the parser, while the configurations are shared read-only
+func (p *Parser) Parse(input []byte) ([]telegraf.Metric, error) {
+	p.initDeltas()
+	if err := p.loadLookups(); err != nil {
+		return nil, err
+	}
+	parser := *p
+	if parser.JSONLines {
+		return parser.parseLines(input)
+	}
+	return parser.parse(input)
+}
+
+// ParseWithTime parses the input like Parse, but uses the given time instead of TimeFunc for the configurations
+// without a timestamp, e.g. the time of the request carrying the input
+func (p *Parser) ParseWithTime(input []byte, t time.Time) ([]telegraf.Metric, error) {
+	p.initDeltas()
+	if err := p.loadLookups(); err != nil {
+		return nil, err
+	}
+	parser := *p
+	parser.TimeFunc = func() time.Time { return t }
+	if parser.JSONLines {
+		return parser.parseLines(input)
+	}
+	return parser.parse(input)
+}
+
+// parseLines parses every line of the input as a separate JSON document, like ParseReader but with lines only
+// limited by MaxBodySize, as the input is already in memory
+func (p *Parser) parseLines(input []byte) ([]telegraf.Metric, error) {
+	if p.MaxBodySize > 0 && len(input) > p.MaxBodySize {
+		return nil, fmt.Errorf("input of %d bytes exceeds the maximum body size of %d bytes", len(input), p.MaxBodySize)
+	}
+	if p.MaxLineSize <= 0 {
+		p.MaxLineSize = len(input) + 1
+	}
+	return p.ParseReader(bytes.NewReader(input), nil)
+}
+
+func (p *Parser) parse(input []byte) ([]telegraf.Metric, error) {
+	// Blank inputs hold no metrics, rather than being invalid JSON, while whitespace is valid in binary formats
+	binaryFormat := p.Format == "msgpack" || p.Format == "cbor"
+	if !binaryFormat {
+		input = bytes.TrimPrefix(input, utf8BOM)
+	}
+	if len(input) == 0 || (!binaryFormat && len(bytes.TrimSpace(input)) == 0) {
+		return []telegraf.Metric{}, nil
+	}
+
+	if p.StripPrefixRegex != "" {
+		if p.stripPrefix == nil {
+			if err := p.compileStripPrefix(); err != nil {
+				return nil, err
+			}
+		}
+		if loc := p.stripPrefix.FindIndex(input); loc != nil {
+			input = input[loc[1]:]
 		}
+	}
 
-		tags, err := p.processMetric(c.Tags, input, true)
+	// Guard against hostile inputs before validating them, as the validation has to decode the whole input
+	if p.MaxBodySize > 0 && len(input) > p.MaxBodySize {
+		return nil, fmt.Errorf("input of %d bytes exceeds the maximum body size of %d bytes", len(input), p.MaxBodySize)
+	}
+	if binaryFormat {
+		decoded, err := p.decodeBinary(input)
 		if err != nil {
 			return nil, err
 		}
+		input = decoded
+	}
+	if p.MaxDepth > 0 {
+		if err := checkDepth(input, p.MaxDepth); err != nil {
+			return nil, err
+		}
+	}
+
+	// Only valid JSON is supported
+	if !gjson.ValidBytes(input) {
+		if p.ParseErrorsMeasurement != "" {
+			return []telegraf.Metric{p.parseErrorsMetric(1, input)}, nil
+		}
+		return nil, fmt.Errorf("Invalid JSON provided, unable to parse")
+	}
+
+	// Share the query results between all configurations, as they often query the same timestamp or tags
+	p.queries = nil
+	if len(p.Configs) > 1 {
+		p.queries = newQueryCache()
+	}
+
+	var metrics []telegraf.Metric
 
-		objects, err := p.processObjects(c.JSONObjects, input)
+	var skipped []string
+	for i, c := range p.Configs {
+		m, err := p.processRoot(c, input, metrics)
 		if err != nil {
-			return nil, err
+			if p.OnError != "skip" {
+				return nil, err
+			}
+			p.Log.Debugf("Skipping configuration %d: %v", i, err)
+			skipped = append(skipped, err.Error())
+			continue
+		}
+		metrics = m
+	}
+
+	for k, v := range p.DefaultTags {
+		for _, t := range metrics {
+			t.AddTag(k, v)
+		}
+	}
+
+	if p.TrimSpace || p.InvalidUTF8 != "" || p.UnicodeNormalization != "" {
+		for _, m := range metrics {
+			rewriteStrings(m, p.cleanString)
+		}
+	}
+
+	if p.AllFieldsAsStrings {
+		for _, m := range metrics {
+			for _, f := range m.FieldList() {
+				v, err := internal.ToString(f.Value)
+				if err != nil {
+					return nil, fmt.Errorf("Unable to convert field '%s' to type string: %v", f.Key, err)
+				}
+				m.AddField(f.Key, v)
+			}
 		}
+	}
+
+	if p.Dedup {
+		metrics = dedup(metrics)
+	}
+
+	metrics, err := p.limitMetrics(metrics)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(skipped) != 0 {
+		return metrics, fmt.Errorf("skipped %d of %d configurations with errors: %s", len(skipped), len(p.Configs), strings.Join(skipped, "; "))
+	}
+
+	return metrics, nil
+}
+
+// limitMetrics fails or truncates the metrics according to the mode if there are more than MaxMetrics
+func (p *Parser) limitMetrics(metrics []telegraf.Metric) ([]telegraf.Metric, error) {
+	if p.MaxMetrics <= 0 || len(metrics) <= p.MaxMetrics {
+		return metrics, nil
+	}
+	if p.MaxMetricsMode != "truncate" {
+		return nil, fmt.Errorf("input resulted in %d metrics, more than the maximum of %d", len(metrics), p.MaxMetrics)
+	}
+	p.Log.Warnf("Input resulted in %d metrics, only keeping the first %d", len(metrics), p.MaxMetrics)
+	return metrics[:p.MaxMetrics], nil
+}
+
+// dedup keeps only the last metric of every series, i.e. metrics with the same name and tags, the last metric is
+// the one with the latest time or the later one in the input for the same time. The kept metric takes the position
+// of the first metric of its series.
+func dedup(metrics []telegraf.Metric) []telegraf.Metric {
+	index := make(map[uint64]int, len(metrics))
+	deduped := make([]telegraf.Metric, 0, len(metrics))
+	for _, m := range metrics {
+		id := m.HashID()
+		i, ok := index[id]
+		if !ok {
+			index[id] = len(deduped)
+			deduped = append(deduped, m)
+			continue
+		}
+		if !m.Time().Before(deduped[i].Time()) {
+			deduped[i] = m
+		}
+	}
+	return deduped
+}
+
+// deltaState holds the last value of every field emitting deltas, it is shared by the copies of the parser made per
+// call and therefore guarded by a lock
+type deltaState struct {
+	sync.Mutex
+	last map[deltaKey]interface{}
+}
+
+// deltaKey identifies a field of a series, i.e. the measurement name and tags
+type deltaKey struct {
+	series uint64
+	field  string
+}
+
+// apply replaces the numeric values of all delta fields of the metric by the difference to the last value of the
+// series. The first value of a series, or a value with a different type than the last one, has nothing to subtract,
+// so the field is removed.
+func (s *deltaState) apply(m telegraf.Metric, sets []DataSet) {
+	var id uint64
+	for _, d := range sets {
+		if !d.Delta {
+			continue
+		}
+		name := d.name()
+		v, ok := m.GetField(name)
+		if !ok {
+			continue
+		}
+		switch v.(type) {
+		case int64, uint64, float64:
+		default:
+			continue
+		}
+		if id == 0 {
+			id = m.HashID()
+		}
+		key := deltaKey{series: id, field: name}
+
+		s.Lock()
+		last, seen := s.last[key]
+		s.last[key] = v
+		s.Unlock()
+
+		delta, ok := subtract(v, last, d.DeltaReset == "zero")
+		switch {
+		case !seen || !ok:
+			m.RemoveField(name)
+		default:
+			m.AddField(name, delta)
+		}
+	}
+}
+
+// subtract returns the difference of the numeric values v and last of the same type, when v is lower than last the
+// counter was reset and v or zero is returned
+func subtract(v, last interface{}, zero bool) (interface{}, bool) {
+	switch v := v.(type) {
+	case int64:
+		l, ok := last.(int64)
+		if !ok {
+			return nil, false
+		}
+		if v < l {
+			if zero {
+				return int64(0), true
+			}
+			return v, true
+		}
+		return v - l, true
+	case uint64:
+		l, ok := last.(uint64)
+		if !ok {
+			return nil, false
+		}
+		if v < l {
+			if zero {
+				return uint64(0), true
+			}
+			return v, true
+		}
+		return v - l, true
+	case float64:
+		l, ok := last.(float64)
+		if !ok {
+			return nil, false
+		}
+		if v < l {
+			if zero {
+				return float64(0), true
+			}
+			return v, true
+		}
+		return v - l, true
+	}
+	return nil, false
+}
+
+// decodeBinary converts a MessagePack or CBOR input to JSON
+func (p *Parser) decodeBinary(input []byte) ([]byte, error) {
+	if p.Format == "cbor" {
+		decoded, err := cborToJSON(input)
+		if err != nil {
+			return nil, fmt.Errorf("decoding CBOR failed: %w", err)
+		}
+		return decoded, nil
+	}
+
+	var buf bytes.Buffer
+	rest, err := msgp.UnmarshalAsJSON(&buf, input)
+	if err != nil {
+		return nil, fmt.Errorf("decoding MessagePack failed: %w", err)
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("decoding MessagePack failed: %d bytes after the end of the document", len(rest))
+	}
+	return buf.Bytes(), nil
+}
+
+// errObjectMatch is returned with StrictObjects if a 'field' or 'tag' path matches an object instead of a value
+var errObjectMatch = errors.New("path matched an object, use type 'json', 'flatten' or an 'object' to gather it")
+
+var normalizationForms = map[string]norm.Form{
+	"NFC":  norm.NFC,
+	"NFD":  norm.NFD,
+	"NFKC": norm.NFKC,
+	"NFKD": norm.NFKD,
+}
+
+// cleanString applies the handling of invalid UTF-8, the Unicode normalization and the trimming to the string
+func (p *Parser) cleanString(s string) string {
+	switch p.InvalidUTF8 {
+	case "replace":
+		s = strings.ToValidUTF8(s, "\uFFFD")
+	case "drop":
+		s = strings.ToValidUTF8(s, "")
+	}
+	if form, ok := normalizationForms[strings.ToUpper(p.UnicodeNormalization)]; ok {
+		s = form.String(s)
+	}
+	if p.TrimSpace {
+		s = strings.TrimSpace(s)
+	}
+	return s
+}
+
+// rewriteStrings rewrites the tags and string fields of the metric, including the keys taken from the input, so
+// e.g. " prod " and "prod" result in the same series after trimming
+func rewriteStrings(m telegraf.Metric, rewrite func(string) string) {
+	tags := append([]*telegraf.Tag(nil), m.TagList()...)
+	for _, t := range tags {
+		m.RemoveTag(t.Key)
+	}
+	for _, t := range tags {
+		m.AddTag(rewrite(t.Key), rewrite(t.Value))
+	}
+	fields := append([]*telegraf.Field(nil), m.FieldList()...)
+	for _, f := range fields {
+		m.RemoveField(f.Key)
+	}
+	for _, f := range fields {
+		value := f.Value
+		if s, ok := value.(string); ok {
+			value = rewrite(s)
+		}
+		m.AddField(rewrite(f.Key), value)
+	}
+}
+
+// keyName returns the key of an object as name, with spaces replaced by underscores after trimming it
+func (p *Parser) keyName(key gjson.Result) string {
+	k := key.String()
+	if p.TrimSpace {
+		k = strings.TrimSpace(k)
+	}
+	return strings.ReplaceAll(k, " ", "_")
+}
+
+// compileStripPrefix compiles the regular expression of the prefix, anchored to the start of the input
+func (p *Parser) compileStripPrefix() error {
+	if p.StripPrefixRegex == "" {
+		return nil
+	}
+	re, err := regexp.Compile("^(?:" + p.StripPrefixRegex + ")")
+	if err != nil {
+		return fmt.Errorf("invalid strip_prefix_regex '%s': %v", p.StripPrefixRegex, err)
+	}
+	p.stripPrefix = re
+	return nil
+}
+
+// measurementNamePlaceholder matches a query in the measurement name like "{service}" in "app_{service}"
+var measurementNamePlaceholder = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// expandMeasurementName replaces the placeholders of the measurement name by the results of their queries, queries
+// not matching a single value are replaced by an empty string
+func expandMeasurementName(name string, input []byte) string {
+	if !strings.Contains(name, "{") {
+		return name
+	}
+	return measurementNamePlaceholder.ReplaceAllStringFunc(name, func(placeholder string) string {
+		result := getPath(input, placeholder[1:len(placeholder)-1])
+		if result.IsArray() || result.IsObject() {
+			return ""
+		}
+		return result.String()
+	})
+}
+
+// checkDepth scans the input for the nesting of objects and arrays, stopping as soon as the maximum depth is exceeded
+func checkDepth(input []byte, maxDepth int) error {
+	var depth int
+	var inString, escaped bool
+	for _, b := range input {
+		switch {
+		case escaped:
+			escaped = false
+		case inString:
+			switch b {
+			case '\\':
+				escaped = true
+			case '"':
+				inString = false
+			}
+		case b == '"':
+			inString = true
+		case b == '{' || b == '[':
+			depth++
+			if depth > maxDepth {
+				return fmt.Errorf("input exceeds the maximum depth of %d nested objects or arrays", maxDepth)
+			}
+		case b == '}' || b == ']':
+			depth--
+		}
+	}
+	return nil
+}
+
+// processRoot will process the configuration for the document at the root path, or for every element of the
+// document if the root path returns an array. Without a root path the whole input is processed.
+func (p *Parser) processRoot(c Config, input []byte, metrics []telegraf.Metric) ([]telegraf.Metric, error) {
+	if c.RootPath == "" {
+		return p.processConfig(c, input, metrics)
+	}
+
+	root := p.getPath(input, c.RootPath)
+	if !root.Exists() || root.Type == gjson.Null {
+		p.Log.Debugf("Root path '%s' not found, skipping measurement '%s'", c.RootPath, c.MeasurementName)
+		return metrics, nil
+	}
+	if !root.IsArray() {
+		return p.processConfig(c, subDocument(input, root, 0), metrics)
+	}
+
+	for i, element := range root.Array() {
+		if c.SampleEveryN > 1 && i%c.SampleEveryN != 0 {
+			continue
+		}
+		m, err := p.processConfig(c, subDocument(input, element, root.Index), nil)
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, m...)
+	}
+	return metrics, nil
+}
+
+// processConfig will add the metrics of the configuration to the metrics created by the previous configurations
+func (p *Parser) processConfig(c Config, input []byte, metrics []telegraf.Metric) ([]telegraf.Metric, error) {
+	// Use the wrapped value as document if the input is wrapped, otherwise the input itself
+	doc := input
+	if c.Unwrap != "" {
+		wrapped := p.getPath(input, c.Unwrap)
+		if wrapped.IsObject() || wrapped.IsArray() {
+			doc = subDocument(input, wrapped, 0)
+		}
+	}
+
+	// Skip the config if the document doesn't satisfy all filters
+	ok, err := filtersMatch(c.Filters, doc)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return metrics, nil
+	}
+
+	// Skip the config if a required tag is missing, as the metrics would end up in a different series
+	for _, t := range c.Tags {
+		if !t.Required {
+			continue
+		}
+		if result, err := p.query(&t, doc); err != nil || !result.Exists() || result.Type == gjson.Null {
+			p.Log.Debugf("Required tag '%s' (path '%s') is missing, dropping metrics of measurement '%s'", t.name(), t.Path, c.MeasurementName)
+			return metrics, nil
+		}
+	}
+
+	p.indexTag = c.IndexTag
+	p.sampleEveryN = c.SampleEveryN
+	p.tagPrecedence = c.TagPrecedence
+
+	// Measurement name configuration
+	p.measurementName = expandMeasurementName(c.MeasurementName, doc)
+	if c.MeasurementNamePath != "" {
+		// Keep the measurement name if the input has no name, as metrics without a name are invalid
+		result := p.getPath(doc, c.MeasurementNamePath)
+		if name := result.String(); result.Type != gjson.Null && name != "" && !result.IsArray() && !result.IsObject() {
+			p.measurementName = name
+		}
+	}
+
+	// Timestamp configuration, every configuration starts from its own default so it doesn't depend on the others
+	p.Timestamp = p.now()
+	if c.TimestampDefault != "" {
+		// Parsers used without Init parse the default here
+		if c.defaultTime.IsZero() {
+			if err := p.parseTimestampDefault(&c); err != nil {
+				return nil, err
+			}
+		}
+		p.Timestamp = c.defaultTime
+	}
+	if c.TimestampPath != "" {
+		result := p.getPath(doc, c.TimestampPath)
+		// Keep the current time if the input has no timestamp
+		if result.Exists() && result.Type != gjson.Null && !result.IsArray() && !result.IsObject() {
+			formats := timestampFormats(c.TimestampFormat, c.TimestampFormats)
+			if len(formats) == 0 {
+				err := fmt.Errorf("use of 'timestamp_query' requires 'timestamp_format'")
+				return nil, err
+			}
+
+			t, err := p.parseTimestamp(result, formats, c.TimestampTimezone)
+			switch {
+			case err == nil:
+				p.Timestamp = t
+			case c.TimestampDefault != "":
+				p.Log.Debugf("Using the default timestamp of measurement '%s', timestamp (path '%s'): %v", p.measurementName, c.TimestampPath, err)
+			default:
+				return nil, fmt.Errorf("measurement '%s', timestamp (path '%s'): %w", p.measurementName, c.TimestampPath, err)
+			}
+		}
+	}
+
+	var fields, tags []telegraf.Metric
+	var done bool
+	if c.simple {
+		fields, done, err = p.processSimple(c, doc)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !done {
+		fields, err = p.processMetric(c.fields(), doc, false)
+		if err != nil {
+			return nil, err
+		}
+
+		tags, err = p.processMetric(c.Tags, doc, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	objects, err := p.processObjects(c.JSONObjects, doc)
+	if err != nil {
+		return nil, err
+	}
+
+	groups, err := p.processGroups(c.Groups, doc)
+	if err != nil {
+		return nil, err
+	}
+	fields = cartesianProduct(fields, groups)
+
+	start := len(metrics)
+	metrics = append(metrics, cartesianProduct(tags, fields)...)
+
+	if len(objects) != 0 && len(metrics) != 0 {
+		metrics = append(metrics, cartesianProduct(objects, metrics)...)
+	} else {
+		metrics = append(metrics, objects...)
+	}
+
+	if p.TimePrecision > 0 {
+		for _, m := range metrics[start:] {
+			m.SetTime(m.Time().Truncate(p.TimePrecision))
+		}
+	}
+
+	if c.TotalCountField != "" {
+		total := int64(len(metrics) - start)
+		for _, m := range metrics[start:] {
+			m.AddField(c.TotalCountField, total)
+		}
+	}
+
+	for _, l := range c.Lookups {
+		for _, m := range metrics[start:] {
+			l.apply(m)
+		}
+	}
+
+	for k, v := range c.DefaultTags {
+		for _, m := range metrics[start:] {
+			if !m.HasTag(k) {
+				m.AddTag(k, v)
+			}
+		}
+	}
+
+	// Deltas are computed per series, so they are applied after adding the default tags but before the expiry tag
+	// varying with the time of every metric
+	if p.deltas != nil {
+		for _, m := range metrics[start:] {
+			p.deltas.apply(m, c.fields())
+		}
+	}
+
+	if c.HashTag != "" {
+		for _, m := range metrics[start:] {
+			addSeriesHash(m, c)
+		}
+	}
+
+	if c.ExpiryTag != "" {
+		for _, m := range metrics[start:] {
+			m.AddTag(c.ExpiryTag, m.Time().Add(c.ExpiryAfter).UTC().Format(time.RFC3339Nano))
+		}
+	}
+
+	if c.EmitMatchCoverage {
+		matched := matchedQueries(c, doc)
+		for _, m := range metrics[start:] {
+			m.AddField("_matched_queries", matched)
+		}
+	}
+
+	if c.DropEmptyMetrics {
+		kept := metrics[:start]
+		for _, m := range metrics[start:] {
+			if len(m.FieldList()) != 0 {
+				kept = append(kept, m)
+			}
+		}
+		metrics = kept
+	}
+
+	// The count is added last to reflect the metrics kept after all filters
+	if c.EmitCount {
+		name := c.CountMeasurement
+		if name == "" {
+			name = p.measurementName + "_count"
+		}
+		count := metric.New(name, map[string]string{}, map[string]interface{}{"n": int64(len(metrics) - start)}, p.Timestamp)
+		for k, v := range c.DefaultTags {
+			count.AddTag(k, v)
+		}
+		metrics = append(metrics, count)
+	}
+
+	return metrics, nil
+}
+
+// addSeriesHash adds the hash of the values of the hash keys as tag, replacing the tags used as keys
+// Fields used as keys are kept. The values are hashed in the order of the keys along with the keys themselves, so
+// the same values always result in the same hash while e.g. swapped values of two keys don't.
+func addSeriesHash(m telegraf.Metric, c Config) {
+	var h hash.Hash64
+	switch c.HashAlgorithm {
+	case "xxhash":
+		h = xxhash.New()
+	default:
+		h = fnv.New64a()
+	}
+
+	for _, key := range c.HashKeys {
+		h.Write([]byte(key))
+		if v, ok := m.GetTag(key); ok {
+			h.Write([]byte{0})
+			h.Write([]byte(v))
+			m.RemoveTag(key)
+		} else if v, ok := m.GetField(key); ok {
+			h.Write([]byte{0})
+			fmt.Fprint(h, v)
+		} else {
+			// Distinguish a missing value from an empty one
+			h.Write([]byte{1})
+		}
+		h.Write([]byte{'\n'})
+	}
+	m.AddTag(c.HashTag, fmt.Sprintf("%016x", h.Sum64()))
+}
+
+// timestampValue returns the value of the result to parse as timestamp
+// Numbers are passed as written in the input for the unix formats, so fractional seconds like 1609459200.123
+// are not subject to the rounding errors of a float. Strings like "1609459200" are accepted if they hold a number.
+func timestampValue(result gjson.Result, format string) (interface{}, error) {
+	if !strings.HasPrefix(format, "unix") || (result.Type != gjson.Number && result.Type != gjson.String) {
+		return result.Value(), nil
+	}
+
+	raw := result.Raw
+	if result.Type == gjson.String {
+		raw = strings.TrimSpace(result.String())
+	}
+	// Both '.' and ',' are accepted as decimal point by the unix formats
+	f, err := strconv.ParseFloat(strings.Replace(raw, ",", ".", 1), 64)
+	if err != nil {
+		return nil, fmt.Errorf("timestamp %q is not a number as required by format '%s'", raw, format)
+	}
+	if strings.ContainsAny(raw, "eE") {
+		return f, nil
+	}
+	return raw, nil
+}
+
+// processSimple adds the values of all fields and tags of a simple configuration to a single metric, looking them up
+// directly instead of expanding them. It returns false if a value is an array or object, which requires processing
+// the configuration as usual.
+func (p *Parser) processSimple(c Config, input []byte) ([]telegraf.Metric, bool, error) {
+	m := metric.New(
+		p.measurementName,
+		map[string]string{},
+		map[string]interface{}{},
+		p.Timestamp,
+	)
+	for _, list := range []dataSetList{{"field", c.fields()}, {"tag", c.Tags}} {
+		for _, d := range list.sets {
+			result := gjson.GetBytes(input, d.Path)
+			if result.IsArray() || result.IsObject() {
+				return nil, false, nil
+			}
+			if result.Value() == nil {
+				if err := p.checkMissing(d, result); err != nil {
+					return nil, false, fmt.Errorf("measurement '%s', %s '%s' (path '%s'): %w", p.measurementName, list.kind, d.name(), d.Path, err)
+				}
+				continue
+			}
+			name := d.name()
+			if list.kind == "tag" && c.TagPrecedence == "first" && m.HasTag(name) {
+				continue
+			}
+			err := p.addValue(MetricNode{
+				OutputName:  name,
+				SetName:     name,
+				DesiredType: d.Type,
+				Settings:    d,
+				Tag:         list.kind == "tag",
+				Metric:      m,
+				Result:      result,
+			})
+			if err != nil {
+				return nil, false, fmt.Errorf("measurement '%s', %s '%s' (path '%s'): %w", p.measurementName, list.kind, name, d.Path, err)
+			}
+		}
+	}
+
+	// Like the general processing, missing values result in an empty metric dropped only with drop_empty_metrics
+	return []telegraf.Metric{m}, true, nil
+}
+
+// processGroups creates the metrics of the fields and tags of all groups, with the fields of all groups combined
+// Groups whose root path doesn't match are skipped.
+func (p *Parser) processGroups(groups []Group, input []byte) ([]telegraf.Metric, error) {
+	var metrics []telegraf.Metric
+	for _, g := range groups {
+		root := p.getPath(input, g.RootPath)
+		if !root.Exists() || root.Type == gjson.Null {
+			p.Log.Debugf("Root path '%s' of group doesn't match, skipping it", g.RootPath)
+			continue
+		}
+		doc := []byte(root.Raw)
+
+		fields, err := p.processMetric(g.Fields, doc, false)
+		if err != nil {
+			return nil, fmt.Errorf("group '%s': %w", g.RootPath, err)
+		}
+		tags, err := p.processMetric(g.Tags, doc, true)
+		if err != nil {
+			return nil, fmt.Errorf("group '%s': %w", g.RootPath, err)
+		}
+
+		grouped := cartesianProduct(tags, fields)
+		if g.Prefix != "" {
+			for _, m := range grouped {
+				prefixKeys(m, g.Prefix)
+			}
+		}
+		metrics = cartesianProduct(metrics, grouped)
+	}
+	return metrics, nil
+}
+
+// prefixKeys prepends the prefix to the keys of all fields and tags of the metric, keeping their order
+func prefixKeys(m telegraf.Metric, prefix string) {
+	fields := append([]*telegraf.Field(nil), m.FieldList()...)
+	for _, f := range fields {
+		m.RemoveField(f.Key)
+	}
+	for _, f := range fields {
+		m.AddField(prefix+f.Key, f.Value)
+	}
+	tags := append([]*telegraf.Tag(nil), m.TagList()...)
+	for _, t := range tags {
+		m.RemoveTag(t.Key)
+	}
+	for _, t := range tags {
+		m.AddTag(prefix+t.Key, t.Value)
+	}
+}
+
+// parseTimestampDefault parses the default timestamp of the configuration with its timestamp format, if set
+func (p *Parser) parseTimestampDefault(c *Config) error {
+	if c.TimestampDefault == "" {
+		return nil
+	}
+	formats := timestampFormats(c.TimestampFormat, c.TimestampFormats)
+	if len(formats) == 0 {
+		return fmt.Errorf("timestamp_default of measurement '%s' requires 'timestamp_format'", c.MeasurementName)
+	}
+	strict := *p
+	strict.Strict = true
+	t, err := strict.parseTimestamp(gjson.Result{Type: gjson.String, Str: c.TimestampDefault}, formats, c.TimestampTimezone)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp_default of measurement '%s': %w", c.MeasurementName, err)
+	}
+	c.defaultTime = t
+	return nil
+}
+
+// timestampFormats returns the formats to try in order, the single format first
+func timestampFormats(format string, formats []string) []string {
+	if format == "" {
+		return formats
+	}
+	return append([]string{format}, formats...)
+}
+
+// parseTimestamp parses the timestamp of the result with the first of the formats that succeeds, the unix formats
+// accept numbers stored as strings. If the value is not a number as required by all formats the time is kept, unless
+// the parser is strict.
+func (p *Parser) parseTimestamp(result gjson.Result, formats []string, timezone string) (time.Time, error) {
+	var errs []string
+	var err error
+	notNumber := true
+	for _, format := range formats {
+		var value interface{}
+		value, err = timestampValue(result, format)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		var t time.Time
+		t, err = internal.ParseTimestamp(format, value, timezone)
+		if err == nil {
+			return t, nil
+		}
+		errs = append(errs, err.Error())
+		notNumber = false
+	}
+	if len(formats) > 1 {
+		err = fmt.Errorf("timestamp %q matches none of the formats: %s", result.String(), strings.Join(errs, "; "))
+	}
+	if notNumber {
+		if p.Strict {
+			return time.Time{}, err
+		}
+		p.Log.Debugf("Keeping the time of the metric: %v", err)
+		return p.Timestamp, nil
+	}
+	return time.Time{}, err
+}
+
+// matchedQueries counts the 'field' and 'tag' queries of the config that match anything in the input
+func matchedQueries(c Config, input []byte) int64 {
+	var matched int64
+	for _, sets := range [][]DataSet{c.Fields, c.Tags} {
+		for _, d := range sets {
+			if result, _ := d.query(input); result.Exists() {
+				matched++
+			}
+		}
+	}
+	return matched
+}
+
+// Explain runs all 'field' and 'tag' queries against the input and reports the outcome without creating metrics
+// The result contains an entry per query, keyed by the index of the configuration, the kind and the name such as
+// "json_v2[0].field.cpu", holding whether the query matched, the raw matched value and the converted value or
+// the conversion error. This is meant to help building a configuration against a real input.
+func (p *Parser) Explain(input []byte) (map[string]interface{}, error) {
+	if !gjson.ValidBytes(input) {
+		return nil, fmt.Errorf("Invalid JSON provided, unable to parse")
+	}
+
+	explanation := make(map[string]interface{})
+	for i, c := range p.Configs {
+		doc := input
+		if c.Unwrap != "" {
+			wrapped := getPath(input, c.Unwrap)
+			if wrapped.IsObject() || wrapped.IsArray() {
+				doc = []byte(wrapped.Raw)
+			}
+		}
+
+		for _, kind := range []string{"field", "tag"} {
+			sets := c.fields()
+			if kind == "tag" {
+				sets = c.Tags
+			}
+			for _, d := range sets {
+				key := fmt.Sprintf("json_v2[%d].%s.%s", i, kind, d.name())
+				explanation[key] = p.explainDataSet(d, doc, kind == "tag")
+			}
+		}
+	}
+
+	return explanation, nil
+}
+
+// ValidateAgainst runs all 'field' and 'tag' queries against the sample input and fails if any of them doesn't match
+// a value or the value can't be converted, listing every failing query. Queries with a default value don't need to
+// match. This is meant to check a configuration against a sample of the expected input, e.g. in a CI test.
+func (p *Parser) ValidateAgainst(input []byte) error {
+	if !gjson.ValidBytes(input) {
+		return fmt.Errorf("Invalid JSON provided, unable to parse")
+	}
+
+	var failures []string
+	for i, c := range p.Configs {
+		doc := input
+		if c.Unwrap != "" {
+			wrapped := getPath(input, c.Unwrap)
+			if wrapped.IsObject() || wrapped.IsArray() {
+				doc = []byte(wrapped.Raw)
+			}
+		}
+
+		for _, kind := range []string{"field", "tag"} {
+			sets := c.fields()
+			if kind == "tag" {
+				sets = c.Tags
+			}
+			for _, d := range sets {
+				if d.Value != "" {
+					continue
+				}
+				var reason string
+				result, err := d.query(doc)
+				switch {
+				case err != nil:
+					reason = err.Error()
+				case !result.Exists() || result.Type == gjson.Null:
+					if d.DefaultValue != "" {
+						continue
+					}
+					reason = "doesn't match any value"
+				default:
+					if e, ok := p.explainDataSet(d, doc, kind == "tag")["error"]; ok {
+						reason = e.(string)
+					}
+				}
+				if reason != "" {
+					failures = append(failures, fmt.Sprintf("json_v2[%d] (measurement '%s'), %s '%s' (path '%s'): %s", i, c.MeasurementName, kind, d.name(), d.Path, reason))
+				}
+			}
+		}
+	}
+
+	if len(failures) != 0 {
+		return fmt.Errorf("%d of the queries failed: %s", len(failures), strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+func (p *Parser) explainDataSet(d DataSet, input []byte, tag bool) map[string]interface{} {
+	result, err := d.query(input)
+	entry := map[string]interface{}{
+		"path":    d.Path,
+		"matched": result.Exists(),
+	}
+	if err != nil {
+		entry["error"] = err.Error()
+		return entry
+	}
+	if !result.Exists() {
+		return entry
+	}
+	entry["raw"] = result.Raw
+
+	desiredType := d.Type
+	if tag {
+		desiredType = "string"
+	}
+
+	var convert func(r gjson.Result) (interface{}, error)
+	convert = func(r gjson.Result) (interface{}, error) {
+		switch {
+		case desiredType == "json":
+			return r.Raw, nil
+		case r.IsObject():
+			return nil, fmt.Errorf("path returned an object, use 'object', 'flatten' or type 'json' to gather it")
+		case r.IsArray():
+			var values []interface{}
+			for _, element := range r.Array() {
+				v, err := convert(element)
+				if err != nil {
+					return nil, err
+				}
+				values = append(values, v)
+			}
+			return values, nil
+		case r.Value() == nil:
+			return nil, nil
+		}
+		return p.convertValue(p.value(r, desiredType), desiredType, d.name(), d)
+	}
+
+	v, err := convert(result)
+	if err != nil {
+		entry["error"] = err.Error()
+	} else {
+		entry["value"] = v
+	}
+	return entry
+}
+
+// Paths returns the path of every leaf value in the input in document order, such as "servers[0].load"
+// The paths can be copied as they are into the 'path' of a field or tag. Empty objects and arrays are
+// reported as leaves, a scalar document is reported as "@this". No metrics are created.
+func (p *Parser) Paths(input []byte) ([]string, error) {
+	if !gjson.ValidBytes(input) {
+		return nil, fmt.Errorf("Invalid JSON provided, unable to parse")
+	}
+
+	root := gjson.ParseBytes(input)
+	if !root.IsObject() && !root.IsArray() {
+		return []string{"@this"}, nil
+	}
+
+	var paths []string
+	var walk func(prefix string, r gjson.Result)
+	walk = func(prefix string, r gjson.Result) {
+		if !r.IsObject() && !r.IsArray() {
+			paths = append(paths, prefix)
+			return
+		}
+		index := 0
+		empty := true
+		r.ForEach(func(key, value gjson.Result) bool {
+			var path string
+			if r.IsArray() {
+				path = fmt.Sprintf("%s[%d]", prefix, index)
+				index++
+			} else {
+				path = escapePathKey(key.String())
+				if prefix != "" {
+					path = prefix + "." + path
+				}
+			}
+			empty = false
+			walk(path, value)
+			return true
+		})
+		if empty {
+			paths = append(paths, prefix)
+		}
+	}
+	walk("", root)
+
+	return paths, nil
+}
+
+// filtersMatch will check if the input satisfies all of the given filters
+// A filter whose path doesn't match anything in the input is never satisfied
+func filtersMatch(filters []Filter, input []byte) (bool, error) {
+	for _, f := range filters {
+		if f.Path == "" {
+			return false, fmt.Errorf("GJSON path is required for filter")
+		}
+		result := getPath(input, f.Path)
+		if !result.Exists() || result.IsArray() || result.IsObject() {
+			return false, nil
+		}
+
+		// Compare numbers by their value, also if stored as string, so "5" is less than "10"
+		var cmp int
+		actual, aerr := strconv.ParseFloat(strings.TrimSpace(result.String()), 64)
+		expected, eerr := strconv.ParseFloat(strings.TrimSpace(f.Value), 64)
+		if (result.Type == gjson.Number || result.Type == gjson.String) && aerr == nil && eerr == nil {
+			switch {
+			case actual < expected:
+				cmp = -1
+			case actual > expected:
+				cmp = 1
+			}
+		} else {
+			cmp = strings.Compare(result.String(), f.Value)
+		}
+
+		var ok bool
+		switch f.Operator {
+		case "", "==":
+			ok = cmp == 0
+		case "!=":
+			ok = cmp != 0
+		case ">":
+			ok = cmp > 0
+		case ">=":
+			ok = cmp >= 0
+		case "<":
+			ok = cmp < 0
+		case "<=":
+			ok = cmp <= 0
+		default:
+			return false, fmt.Errorf("unknown filter operator '%s' for path '%s'", f.Operator, f.Path)
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+func (p *Parser) now() time.Time {
+	if p.TimeFunc == nil {
+		return time.Now()
+	}
+	return p.TimeFunc()
+}
+
+// ParseChunked parses the input and hands the resulting metrics to fn in batches of at most chunkSize metrics
+// The batches hold the same metrics as returned by Parse, so options like MaxMetrics and Dedup apply to the whole
+// input. This bounds the number of metrics a consumer has to handle at once when large arrays are expanded
+func (p *Parser) ParseChunked(input []byte, chunkSize int, fn func([]telegraf.Metric) error) error {
+	if chunkSize <= 0 {
+		return fmt.Errorf("chunk size must be greater than zero, got %d", chunkSize)
+	}
+
+	metrics, err := p.Parse(input)
+	if err != nil {
+		return err
+	}
+
+	for start := 0; start < len(metrics); start += chunkSize {
+		end := start + chunkSize
+		if end > len(metrics) {
+			end = len(metrics)
+		}
+		if err := fn(metrics[start:end]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ParseReader reads the input line by line and parses every non-empty line as a separate JSON document
+// The lines are split using split, or bufio.ScanLines if nil, so the input doesn't need to be read into memory at once
+// Lines longer than MaxLineSize fail with bufio.ErrTooLong
+func (p *Parser) ParseReader(r io.Reader, split bufio.SplitFunc) ([]telegraf.Metric, error) {
+	p.initDeltas()
+	if err := p.loadLookups(); err != nil {
+		return nil, err
+	}
+	maxLineSize := p.MaxLineSize
+	if maxLineSize <= 0 {
+		maxLineSize = bufio.MaxScanTokenSize
+	}
+
+	scanner := bufio.NewScanner(r)
+	bufferSize := 4096
+	if bufferSize > maxLineSize {
+		bufferSize = maxLineSize
+	}
+	scanner.Buffer(make([]byte, 0, bufferSize), maxLineSize)
+	if split != nil {
+		scanner.Split(split)
+	}
+
+	var metrics []telegraf.Metric
+	var skipped []string
+	var line int
+	var invalid int64
+	var snippet []byte
+	for scanner.Scan() {
+		line++
+		input := bytes.TrimSpace(bytes.TrimPrefix(scanner.Bytes(), utf8BOM))
+		if len(input) == 0 {
+			continue
+		}
+		if p.ParseErrorsMeasurement != "" && !gjson.ValidBytes(input) {
+			if invalid == 0 {
+				snippet = append(snippet, input...)
+			}
+			invalid++
+			continue
+		}
+		parser := *p
+		parser.line = line
+		m, err := parser.parse(input)
+		if err != nil {
+			if p.OnError != "skip" {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			p.Log.Debugf("Skipping line %d: %v", line, err)
+			skipped = append(skipped, fmt.Sprintf("line %d: %v", line, err))
+		}
+		metrics = append(metrics, m...)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("line %d: %w", line+1, err)
+	}
+
+	if p.Dedup {
+		metrics = dedup(metrics)
+	}
+
+	metrics, err := p.limitMetrics(metrics)
+	if err != nil {
+		return nil, err
+	}
+
+	if invalid > 0 {
+		metrics = append(metrics, p.parseErrorsMetric(invalid, snippet))
+	}
+
+	if len(skipped) != 0 {
+		return metrics, fmt.Errorf("skipped %d of %d lines with errors: %s", len(skipped), line, strings.Join(skipped, "; "))
+	}
+
+	return metrics, nil
+}
+
+// parseErrorsMetric creates the metric reporting the number of invalid inputs
+func (p *Parser) parseErrorsMetric(count int64, input []byte) telegraf.Metric {
+	m := metric.New(
+		p.ParseErrorsMeasurement,
+		map[string]string{},
+		map[string]interface{}{"count": count},
+		p.now(),
+	)
+	if p.ParseErrorsSnippetLength > 0 {
+		snippet := []rune(string(input))
+		if len(snippet) > p.ParseErrorsSnippetLength {
+			snippet = snippet[:p.ParseErrorsSnippetLength]
+		}
+		m.AddTag("snippet", string(snippet))
+	}
+	for k, v := range p.DefaultTags {
+		m.AddTag(k, v)
+	}
+	return m
+}
+
+// checkMissing returns an error for a missing or null value with on_missing 'error', unless the value is optional
+func (p *Parser) checkMissing(d DataSet, result gjson.Result) error {
+	if p.OnMissing != "error" || d.Optional || d.Value != "" || (result.Exists() && result.Type != gjson.Null) {
+		return nil
+	}
+	return errors.New("value is missing")
+}
+
+// processMetric will iterate over all 'field' or 'tag' configs and create metrics for each
+// A field/tag can either be a single value or an array of values, each resulting in its own metric
+// For multiple configs, a set of metrics is created from the cartesian product of each separate config
+func (p *Parser) processMetric(data []DataSet, input []byte, tag bool) ([]telegraf.Metric, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	p.iterateObjects = false
+	var metrics [][]telegraf.Metric
+
+	for _, c := range data {
+		m, err := p.processDataSet(c, input, tag)
+		if err != nil {
+			kind := "field"
+			if tag {
+				kind = "tag"
+			}
+			return nil, fmt.Errorf("measurement '%s', %s '%s' (path '%s'): %w", p.measurementName, kind, c.name(), c.Path, err)
+		}
+		if m != nil {
+			metrics = append(metrics, m)
+		}
+	}
+
+	if len(metrics) == 0 {
+		return nil, nil
+	}
+
+	// Tags of later queries overwrite the ones of earlier queries with the same name, unless the first one wins
+	keepTags := tag && p.tagPrecedence == "first"
+	for i := 1; i < len(metrics); i++ {
+		metrics[i] = product(metrics[i-1], metrics[i], keepTags)
+	}
+
+	return metrics[len(metrics)-1], nil
+}
+
+// processDataSet will create the metrics for a single 'field' or 'tag' config
+// It returns nil if the config should be ignored, for example because the path returned an object
+func (p *Parser) processDataSet(c DataSet, input []byte, tag bool) ([]telegraf.Metric, error) {
+	if c.Path == "" && c.Value == "" {
+		return nil, fmt.Errorf("GJSON path is required")
+	}
+	// The built-in values are numbers, but more useful as integers than as floats
+	if (c.Value == "@now" || c.Value == "@line") && c.Type == "" {
+		c.Type = "int"
+	}
+
+	// Only add the value if the document satisfies all conditions of the field or tag
+	ok, err := filtersMatch(c.Conditions, input)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, nil
+	}
+
+	result, err := p.query(&c, input)
+	if err != nil {
+		if p.Strict {
+			return nil, err
+		}
+		p.Log.Debugf("Skipping value: %v", err)
+		return nil, nil
+	}
+
+	if c.MaxMatches > 0 && result.IsArray() {
+		matches := result.Array()
+		if len(matches) > c.MaxMatches {
+			if p.Strict {
+				return nil, fmt.Errorf("found %d matches, more than the maximum of %d", len(matches), c.MaxMatches)
+			}
+			p.Log.Debugf("Found %d matches for path %s, only using the first %d", len(matches), c.Path, c.MaxMatches)
+			raw := make([]string, 0, c.MaxMatches)
+			for _, match := range matches[:c.MaxMatches] {
+				raw = append(raw, match.Raw)
+			}
+			result = gjson.Parse("[" + strings.Join(raw, ",") + "]")
+		}
+	}
+
+	// Empty strings are handled like missing values before converting them, a missing value uses the default
+	if c.EmptyStringAsNull && result.Type == gjson.String && result.Str == "" {
+		result = gjson.Result{}
+	}
+	if c.DefaultValue != "" && (!result.Exists() || result.Type == gjson.Null) {
+		result = gjson.Result{Type: gjson.String, Str: c.DefaultValue, Raw: strconv.Quote(c.DefaultValue)}
+	}
+	if err := p.checkMissing(c, result); err != nil {
+		return nil, err
+	}
+
+	setName := c.name()
+
+	var sourcePath string
+	if c.SourcePathTag != "" {
+		p.document = input
+		sourcePath = c.matchedPath(input)
+	}
+
+	if c.Type == "json" {
+		m, err := p.rawJSON(setName, result, tag)
+		if err != nil || m == nil {
+			return nil, err
+		}
+		tagSourcePath(m, c, sourcePath)
+		return []telegraf.Metric{m}, nil
+	}
+
+	if c.SubParser != nil || c.SubFormat != "" {
+		m, err := p.parseSubFormat(setName, result, c, tag)
+		if err != nil || m == nil {
+			return nil, err
+		}
+		tagSourcePath(m, c, sourcePath)
+		return []telegraf.Metric{m}, nil
+	}
+
+	if c.Split != "" {
+		m, err := p.splitValue(setName, result, c, tag)
+		if err != nil || m == nil {
+			return nil, err
+		}
+		tagSourcePath(m, c, sourcePath)
+		return []telegraf.Metric{m}, nil
+	}
+
+	if c.Aggregate != "" && result.IsArray() {
+		m, err := p.aggregateArray(setName, result, c, tag)
+		if err != nil || m == nil {
+			return nil, err
+		}
+		tagSourcePath(m, c, sourcePath)
+		return []telegraf.Metric{m}, nil
+	}
+
+	if c.JoinArray && result.IsArray() {
+		m, err := p.joinArray(setName, result, c, tag)
+		if err != nil || m == nil {
+			return nil, err
+		}
+		tagSourcePath(m, c, sourcePath)
+		return []telegraf.Metric{m}, nil
+	}
+
+	if c.Flatten && result.IsObject() {
+		if c.keyFilter == nil {
+			if err := c.compileKeyFilter(); err != nil {
+				return nil, err
+			}
+		}
+		m := metric.New(
+			p.measurementName,
+			map[string]string{},
+			map[string]interface{}{},
+			p.Timestamp,
+		)
+		err := p.flatten(m, setName, result, c, tag)
+		if err != nil {
+			return nil, err
+		}
+		tagSourcePath(m, c, sourcePath)
+		return []telegraf.Metric{m}, nil
+	}
+
+	if c.pivots() && !tag && result.IsObject() {
+		return p.pivotObject(setName, result, c, sourcePath)
+	}
+
+	if c.Columnar && !tag && result.IsObject() {
+		if c.keyFilter == nil {
+			if err := c.compileKeyFilter(); err != nil {
+				return nil, err
+			}
+		}
+		return p.zipColumns(result, c)
+	}
+
+	if result.IsObject() {
+		if p.StrictObjects {
+			return nil, errObjectMatch
+		}
+		p.Log.Debugf("Found object in the path: %s, ignoring it please use 'object' to gather metrics from objects", c.Path)
+		return nil, nil
+	}
+
+	mNode := MetricNode{
+		OutputName:  setName,
+		SetName:     setName,
+		DesiredType: c.Type,
+		Settings:    c,
+		Tag:         tag,
+		Metric: metric.New(
+			p.measurementName,
+			map[string]string{},
+			map[string]interface{}{},
+			p.Timestamp,
+		),
+		Result:     result,
+		sourcePath: sourcePath,
+	}
+
+	// Expand all array's and nested arrays into separate metrics
+	nodes, err := p.expandArray(mNode)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.RequireMonotonic {
+		nodes, err = p.dropNonMonotonic(nodes)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	m := make([]telegraf.Metric, 0, len(nodes))
+	for _, n := range nodes {
+		m = append(m, n.Metric)
+	}
+	return m, nil
+}
+
+// rawJSON will create a metric holding the compacted JSON of the query result as a string
+func (p *Parser) rawJSON(name string, result gjson.Result, tag bool) (telegraf.Metric, error) {
+	if !result.Exists() {
+		return nil, nil
+	}
+
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, []byte(result.Raw)); err != nil {
+		return nil, fmt.Errorf("Unable to compact JSON of '%s': %v", name, err)
+	}
+
+	m := metric.New(
+		p.measurementName,
+		map[string]string{},
+		map[string]interface{}{},
+		p.Timestamp,
+	)
+	if tag {
+		m.AddTag(name, buf.String())
+	} else {
+		m.AddField(name, buf.String())
+	}
+	return m, nil
+}
+
+// parseSubFormat will create a metric holding the fields and tags parsed from the string value by the sub-parser
+// The names are prefixed with the name of the field or tag, and can be filtered with include_keys and exclude_keys
+func (p *Parser) parseSubFormat(name string, result gjson.Result, c DataSet, tag bool) (telegraf.Metric, error) {
+	if !result.Exists() || result.Type == gjson.Null {
+		return nil, nil
+	}
+	if result.Type != gjson.String {
+		return nil, fmt.Errorf("Unable to parse '%s' with sub_format '%s': value is not a string", name, c.SubFormat)
+	}
+
+	sp, err := c.subParser()
+	if err != nil {
+		return nil, err
+	}
+	if c.keyFilter == nil {
+		if err := c.compileKeyFilter(); err != nil {
+			return nil, err
+		}
+	}
+
+	parsed, err := sp.Parse([]byte(result.String()))
+	if err != nil {
+		if p.Strict {
+			return nil, fmt.Errorf("Unable to parse '%s' with sub_format '%s': %v", name, c.SubFormat, err)
+		}
+		p.Log.Debugf("Skipping value of '%s' that can't be parsed: %v", name, err)
+		return nil, nil
+	}
+
+	separator := c.FlattenSeparator
+	if separator == "" {
+		separator = "_"
+	}
+
+	m := metric.New(
+		p.measurementName,
+		map[string]string{},
+		map[string]interface{}{},
+		p.Timestamp,
+	)
+	for _, sm := range parsed {
+		for _, t := range sm.TagList() {
+			if c.keyFilter == nil || c.keyFilter.Match(t.Key) {
+				m.AddTag(name+separator+t.Key, t.Value)
+			}
+		}
+		// Sub-parsers might build the fields from a map, so sort them for a stable field order
+		fields := sm.FieldList()
+		sort.SliceStable(fields, func(i, j int) bool { return fields[i].Key < fields[j].Key })
+		for _, f := range fields {
+			if c.keyFilter != nil && !c.keyFilter.Match(f.Key) {
+				continue
+			}
+			if !tag {
+				v := f.Value
+				if p.ForceFloat && c.Type != "int" && c.Type != "uint" {
+					v = toFloat(v)
+				}
+				m.AddField(name+separator+f.Key, v)
+				continue
+			}
+			v, err := internal.ToString(f.Value)
+			if err != nil {
+				return nil, err
+			}
+			m.AddTag(name+separator+f.Key, v)
+		}
+	}
+	return m, nil
+}
+
+// joinArray will create a metric holding the scalar elements of the array joined by the separator as a string
+func (p *Parser) joinArray(name string, result gjson.Result, c DataSet, tag bool) (telegraf.Metric, error) {
+	separator := c.JoinSeparator
+	if separator == "" {
+		separator = ","
+	}
+
+	var elements []string
+	for _, element := range result.Array() {
+		if element.IsObject() || element.IsArray() {
+			if p.Strict {
+				return nil, fmt.Errorf("Unable to join array of '%s': element %s is not a scalar", name, element.Raw)
+			}
+			p.Log.Debugf("Skipping element %s of '%s' as it is not a scalar", element.Raw, name)
+			continue
+		}
+		v, err := p.convertType(element.Value(), "string", name)
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, v.(string))
+	}
+
+	m := metric.New(
+		p.measurementName,
+		map[string]string{},
+		map[string]interface{}{},
+		p.Timestamp,
+	)
+	if tag {
+		m.AddTag(name, strings.Join(elements, separator))
+	} else {
+		m.AddField(name, strings.Join(elements, separator))
+	}
+	return m, nil
+}
+
+// aggregateArray reduces the numbers of the array to a single field or tag holding their sum, average, minimum,
+// maximum or count. The result is a float, or an integer for the count, unless a type is configured.
+func (p *Parser) aggregateArray(name string, result gjson.Result, c DataSet, tag bool) (telegraf.Metric, error) {
+	var sum, min, max float64
+	var count int64
+	for i, element := range result.Array() {
+		if element.Type == gjson.Null {
+			continue
+		}
+		if element.Type != gjson.Number {
+			if p.Strict {
+				return nil, fmt.Errorf("Unable to aggregate array of '%s': element %d (%s) is not a number", name, i, element.Raw)
+			}
+			p.Log.Debugf("Skipping element %s of '%s' as it is not a number", element.Raw, name)
+			continue
+		}
+		v := element.Num
+		if count == 0 || v < min {
+			min = v
+		}
+		if count == 0 || v > max {
+			max = v
+		}
+		sum += v
+		count++
+	}
+
+	// There is no average, minimum or maximum of an empty array
+	if count == 0 && c.Aggregate != "count" && c.Aggregate != "sum" {
+		return nil, nil
+	}
+
+	var v interface{}
+	switch c.Aggregate {
+	case "count":
+		v = count
+	case "sum":
+		v = sum
+	case "avg":
+		v = sum / float64(count)
+	case "min":
+		v = min
+	case "max":
+		v = max
+	}
+	if tag {
+		c.Type = "string"
+	}
+	if c.Type != "" {
+		var err error
+		v, err = p.convertType(v, c.Type, name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	m := metric.New(
+		p.measurementName,
+		map[string]string{},
+		map[string]interface{}{},
+		p.Timestamp,
+	)
+	if tag {
+		m.AddTag(name, v.(string))
+	} else {
+		m.AddField(name, v)
+	}
+	return m, nil
+}
+
+// splitValue splits the string value at the separator into a field or tag for each of the split names, the parts
+// are converted to the split type of their position. Parts beyond the names are ignored, names without a part skipped
+func (p *Parser) splitValue(name string, result gjson.Result, c DataSet, tag bool) (telegraf.Metric, error) {
+	if !result.Exists() || result.Type == gjson.Null {
+		return nil, nil
+	}
+	if result.Type != gjson.String {
+		if p.Strict {
+			return nil, fmt.Errorf("Unable to split '%s': value is not a string", name)
+		}
+		p.Log.Debugf("Skipping value of '%s' as it is not a string to split", name)
+		return nil, nil
+	}
+
+	m := metric.New(
+		p.measurementName,
+		map[string]string{},
+		map[string]interface{}{},
+		p.Timestamp,
+	)
+	parts := strings.Split(result.String(), c.Split)
+	for i, part := range parts {
+		if i >= len(c.SplitNames) {
+			break
+		}
+
+		desiredType := c.Type
+		if i < len(c.SplitTypes) && c.SplitTypes[i] != "" {
+			desiredType = c.SplitTypes[i]
+		}
+		if tag {
+			desiredType = "string"
+		}
+		v, err := p.convertValue(strings.TrimSpace(part), desiredType, c.SplitNames[i], c)
+		if err != nil {
+			if p.Strict {
+				return nil, err
+			}
+			p.Log.Debugf("Skipping value: %v", err)
+			continue
+		}
+
+		if tag {
+			m.AddTag(c.SplitNames[i], v.(string))
+		} else {
+			m.AddField(c.SplitNames[i], v)
+		}
+	}
+	return m, nil
+}
+
+// flatten will add all nested values of an object to the metric, using the keys joined by the separator as name
+// Array elements are added with their index as key, in the same way the JSON parser flattens arrays
+func (p *Parser) flatten(m telegraf.Metric, name string, result gjson.Result, c DataSet, tag bool) error {
+	separator := c.FlattenSeparator
+	if separator == "" {
+		separator = "_"
+	}
+
+	if result.IsObject() || result.IsArray() {
+		var err error
+		i := 0
+		result.ForEach(func(key, val gjson.Result) bool {
+			k := strconv.Itoa(i)
+			if result.IsObject() {
+				k = p.keyName(key)
+			}
+			i++
+			err = p.flatten(m, name+separator+k, val, c, tag)
+			return err == nil
+		})
+		return err
+	}
+
+	if result.Value() == nil {
+		return nil
+	}
+
+	if c.keyFilter != nil && !c.keyFilter.Match(strings.TrimPrefix(name, c.name()+separator)) {
+		return nil
+	}
+
+	desiredType := c.Type
+	if tag {
+		desiredType = "string"
+	}
+	v, err := p.convertValue(p.value(result, desiredType), desiredType, name, c)
+	if err != nil {
+		if p.Strict {
+			return err
+		}
+		p.Log.Debugf("Skipping value: %v", err)
+		return nil
+	}
+	if tag {
+		m.AddTag(name, v.(string))
+	} else {
+		m.AddField(name, v)
+	}
+
+	return nil
+}
+
+// dropNonMonotonic will drop all expanded array elements whose value is lower than the value of a previous element
+func (p *Parser) dropNonMonotonic(nodes []MetricNode) ([]MetricNode, error) {
+	var results []MetricNode
+	var last float64
+	var seen bool
+	for _, n := range nodes {
+		v, ok := n.Metric.GetField(n.OutputName)
+		if !ok {
+			results = append(results, n)
+			continue
+		}
+		current, err := internal.ToFloat64(v)
+		if err != nil {
+			return nil, fmt.Errorf("field '%s' requires monotonic values, but has non-numeric value '%v'", n.OutputName, v)
+		}
+		if seen && current < last {
+			p.Log.Debugf("Dropping value %v of field '%s' as it is lower than the previous value %v", v, n.OutputName, last)
+			continue
+		}
+		last, seen = current, true
+		results = append(results, n)
+	}
+
+	return results, nil
+}
+
+// queryCache holds the results of the queries of a single parse call per document, so the same query of several
+// configurations, like a shared timestamp or tag, is only evaluated once. The documents are keyed by their location
+// in memory instead of their content, so a lookup doesn't need to hash the whole document. Documents within the
+// input, like an unwrapped value, therefore have to be a slice of the input, see subDocument.
+type queryCache struct {
+	documents map[documentKey]map[string]queryResult
+}
+
+// documentKey identifies a document by its first byte and length
+type documentKey struct {
+	start  *byte
+	length int
+}
+
+type queryResult struct {
+	result gjson.Result
+	err    error
+}
+
+func newQueryCache() *queryCache {
+	return &queryCache{documents: make(map[documentKey]map[string]queryResult)}
+}
+
+// lookup returns the cached result of the query with the key within the input or evaluates and caches it
+func (q *queryCache) lookup(input []byte, key string, query func() (gjson.Result, error)) (gjson.Result, error) {
+	if q == nil {
+		return query()
+	}
+	id := documentKey{length: len(input)}
+	if len(input) > 0 {
+		id.start = &input[0]
+	}
+	results, ok := q.documents[id]
+	if !ok {
+		results = make(map[string]queryResult)
+		q.documents[id] = results
+	}
+	if r, ok := results[key]; ok {
+		return r.result, r.err
+	}
+	result, err := query()
+	results[key] = queryResult{result: result, err: err}
+	return result, err
+}
+
+// subDocument returns the value of the result as document, which is the slice of the input holding the value if the
+// result was queried from the input. This shares the query results of the same value between all configurations.
+func subDocument(input []byte, result gjson.Result, offset int) []byte {
+	start := result.Index + offset
+	if result.Index > 0 && start+len(result.Raw) <= len(input) && string(input[start:start+len(result.Raw)]) == result.Raw {
+		return input[start : start+len(result.Raw)]
+	}
+	return []byte(result.Raw)
+}
+
+// getPath is like the function getPath, but shares the results within a parse call
+func (p *Parser) getPath(input []byte, path string) gjson.Result {
+	result, _ := p.queries.lookup(input, path, func() (gjson.Result, error) {
+		return getPath(input, path), nil
+	})
+	return result
+}
+
+// query is like the query of the data set, but shares the results within a parse call
+func (p *Parser) query(d *DataSet, input []byte) (gjson.Result, error) {
+	if d.Value != "" {
+		return p.literal(d.Value), nil
+	}
+	return p.queries.lookup(input, d.queryKey(), func() (gjson.Result, error) {
+		return d.query(input)
+	})
+}
+
+// literal returns the result of a 'value' used instead of a path, that is the current Unix time for "@now", the line
+// number of the input for "@line" or the value itself as string. Outside ParseReader the line number is unknown.
+func (p *Parser) literal(value string) gjson.Result {
+	var n int64
+	switch value {
+	case "@now":
+		n = p.now().Unix()
+	case "@line":
+		if p.line == 0 {
+			return gjson.Result{}
+		}
+		n = int64(p.line)
+	default:
+		return gjson.Result{Type: gjson.String, Str: value, Raw: strconv.Quote(value)}
+	}
+	return gjson.Result{Type: gjson.Number, Num: float64(n), Raw: strconv.FormatInt(n, 10)}
+}
+
+// getPath runs the GJSON query against the input, resolving negative array indices like 'readings.-1.value'
+// against the length of the array they are applied to, as GJSON itself only supports positive indices
+// A path starting with '$..' searches the whole input recursively, see recursiveDescent, and JSONPath filter
+// expressions are converted, see convertJSONPath
+func getPath(input []byte, path string) gjson.Result {
+	if strings.HasPrefix(path, recursiveDescentPrefix) {
+		return recursiveDescent(input, strings.TrimPrefix(path, recursiveDescentPrefix))
+	}
+	return gjson.GetBytes(input, resolvePath(input, path))
+}
+
+// resolvePath turns the path into a plain GJSON path with positive array indices
+func resolvePath(input []byte, path string) string {
+	if converted, err := convertJSONPath(path); err == nil {
+		path = converted
+	}
+	return resolveNegativeIndices(input, bracketIndices(path))
+}
+
+// filterExpression matches a JSONPath filter expression in brackets like "[?(@.status=='ok')]"
+var filterExpression = regexp.MustCompile(`\.?\[\?\((.*?)\)\]`)
+
+// filterComparison matches the condition of a filter expression, '@' or a key of the element with an optional
+// comparison, like "@.status == 'ok'"
+var filterComparison = regexp.MustCompile(`^@(\.[^=!<>]+)?\s*(?:(==|!=|<=|>=|<|>)(.+))?$`)
+
+// convertJSONPath converts the JSONPath syntax of the path to GJSON. The root '$.' is dropped and filter expressions
+// become queries returning all matching elements, so "$.items[?(@.status=='ok')].latency" becomes
+// "items.#(status=="ok")#.latency". Conditions combined with '&&' or '||' are not supported.
+func convertJSONPath(path string) (string, error) {
+	if path == "$" {
+		return "@this", nil
+	}
+	if strings.HasPrefix(path, "$.") && !strings.HasPrefix(path, recursiveDescentPrefix) {
+		path = path[2:]
+	}
+	if !strings.Contains(path, "[?(") {
+		return path, nil
+	}
+
+	var err error
+	path = filterExpression.ReplaceAllStringFunc(path, func(match string) string {
+		query, qerr := filterQuery(filterExpression.FindStringSubmatch(match)[1])
+		if qerr != nil && err == nil {
+			err = qerr
+		}
+		return ".#(" + query + ")#"
+	})
+	return strings.TrimPrefix(path, "."), err
+}
+
+// filterQuery converts the condition of a JSONPath filter expression to a GJSON query
+func filterQuery(condition string) (string, error) {
+	condition = strings.TrimSpace(condition)
+	if strings.Contains(condition, "&&") || strings.Contains(condition, "||") {
+		return "", fmt.Errorf("filter '%s' combines conditions, which is not supported", condition)
+	}
+	match := filterComparison.FindStringSubmatch(condition)
+	if match == nil {
+		return "", fmt.Errorf("invalid filter '%s', must compare '@' or '@.key' with a value", condition)
+	}
+
+	key := strings.TrimSpace(strings.TrimPrefix(match[1], "."))
+	if match[2] == "" {
+		if key == "" {
+			return "", fmt.Errorf("invalid filter '%s', must compare '@' with a value", condition)
+		}
+		// Elements having the key
+		return key, nil
+	}
+	value := strings.TrimSpace(match[3])
+	if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+		value = strconv.Quote(value[1 : len(value)-1])
+	}
+	return key + match[2] + value, nil
+}
+
+// bracketIndex matches an array index in brackets like "[1]" in "values[1]", with an optional leading dot
+var bracketIndex = regexp.MustCompile(`\.?\[(-?\d+)\]`)
 
-		metrics = append(metrics, cartesianProduct(tags, fields)...)
+// bracketIndices converts array indices in brackets to the GJSON syntax, so "values[1]" becomes "values.1" and
+// "[0]" queries the first element of the document, like in JSONPath
+func bracketIndices(path string) string {
+	if !strings.Contains(path, "[") {
+		return path
+	}
+	return strings.TrimPrefix(bracketIndex.ReplaceAllString(path, ".$1"), ".")
+}
 
-		if len(objects) != 0 && len(metrics) != 0 {
-			metrics = append(metrics, cartesianProduct(objects, metrics)...)
-		} else {
-			metrics = append(metrics, objects...)
-		}
+const recursiveDescentPrefix = "$.."
+
+// recursiveDescent searches the input for all object keys named like the first part of the path, in the same way
+// as '$..price' in JSONPath, and applies the remaining path to every value found. The results are returned as an
+// array in the order of the input
+func recursiveDescent(input []byte, path string) gjson.Result {
+	matches, _ := recursiveDescentMatches(input, path)
+	if len(matches) == 0 {
+		return gjson.Result{}
 	}
+	return gjson.Parse("[" + strings.Join(matches, ",") + "]")
+}
 
-	for k, v := range p.DefaultTags {
-		for _, t := range metrics {
-			t.AddTag(k, v)
-		}
+// recursiveDescentMatches returns the raw values found by recursiveDescent along with the path of every value
+func recursiveDescentMatches(input []byte, path string) ([]string, []string) {
+	if converted, err := convertJSONPath(path); err == nil {
+		path = converted
+	}
+	key, rest := path, ""
+	if i := strings.Index(path, "."); i >= 0 {
+		key, rest = path[:i], path[i+1:]
 	}
 
-	return metrics, nil
+	var matches, paths []string
+	var walk func(r gjson.Result, prefix string)
+	walk = func(r gjson.Result, prefix string) {
+		var index int
+		r.ForEach(func(k, v gjson.Result) bool {
+			// The keys of array elements are empty, so their index is used in the path
+			keyPath := prefix + strconv.Itoa(index)
+			if r.IsObject() {
+				keyPath = prefix + escapePathKey(k.String())
+			}
+			index++
+			if r.IsObject() && k.String() == key {
+				if rest == "" {
+					matches = append(matches, v.Raw)
+					paths = append(paths, keyPath)
+				} else if match := getPath([]byte(v.Raw), rest); match.Exists() {
+					matches = append(matches, match.Raw)
+					paths = append(paths, keyPath+"."+rest)
+				}
+			}
+			if v.IsObject() || v.IsArray() {
+				walk(v, keyPath+".")
+			}
+			return true
+		})
+	}
+	walk(gjson.ParseBytes(input), "")
+	return matches, paths
 }
 
-// processMetric will iterate over all 'field' or 'tag' configs and create metrics for each
-// A field/tag can either be a single value or an array of values, each resulting in its own metric
-// For multiple configs, a set of metrics is created from the cartesian product of each separate config
-func (p *Parser) processMetric(data []DataSet, input []byte, tag bool) ([]telegraf.Metric, error) {
-	if len(data) == 0 {
-		return nil, nil
+// escapePathKey escapes the characters of an object key having a special meaning in a GJSON path
+func escapePathKey(key string) string {
+	var b strings.Builder
+	for _, r := range key {
+		if strings.ContainsRune(`.*?|#@\`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
 	}
+	return b.String()
+}
 
-	p.iterateObjects = false
-	var metrics [][]telegraf.Metric
+// splitPath splits a GJSON path into its components at the dots not escaped by a backslash
+func splitPath(path string) []string {
+	var parts []string
+	var escaped bool
+	start := 0
+	for i := 0; i < len(path); i++ {
+		switch {
+		case escaped:
+			escaped = false
+		case path[i] == '\\':
+			escaped = true
+		case path[i] == '.':
+			parts = append(parts, path[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, path[start:])
+}
 
-	for _, c := range data {
-		if c.Path == "" {
-			return nil, fmt.Errorf("GJSON path is required")
+// elementPath returns the concrete path of the element with the given index in the array found at the path
+// For a path with a '#' wildcard like "servers.#.load" the index counts the elements the rest of the path exists
+// for, as these are the ones returned by the query, so the second match might result in "servers.2.load"
+func (p *Parser) elementPath(path string, index int) string {
+	if strings.HasPrefix(path, recursiveDescentPrefix) {
+		_, paths := recursiveDescentMatches(p.document, strings.TrimPrefix(path, recursiveDescentPrefix))
+		if index < len(paths) {
+			return paths[index]
 		}
-		result := gjson.GetBytes(input, c.Path)
+		return path
+	}
 
-		if result.IsObject() {
-			p.Log.Debugf("Found object in the path: %s, ignoring it please use 'object' to gather metrics from objects", c.Path)
+	parts := splitPath(path)
+	for i, part := range parts {
+		if part != "#" {
 			continue
 		}
-
-		setName := c.Rename
-		// Default to the last path word, should be the upper key name
-		if setName == "" {
-			s := strings.Split(c.Path, ".")
-			setName = s[len(s)-1]
+		prefix := strings.Join(parts[:i], ".")
+		rest := strings.Join(parts[i+1:], ".")
+		countPath := "#"
+		if prefix != "" {
+			countPath = prefix + ".#"
+			prefix += "."
 		}
-		setName = strings.ReplaceAll(setName, " ", "_")
 
-		mNode := MetricNode{
-			OutputName:  setName,
-			SetName:     setName,
-			DesiredType: c.Type,
-			Tag:         tag,
-			Metric: metric.New(
-				p.measurementName,
-				map[string]string{},
-				map[string]interface{}{},
-				p.Timestamp,
-			),
-			Result: result,
+		matches := 0
+		count := int(gjson.GetBytes(p.document, countPath).Int())
+		for k := 0; k < count; k++ {
+			candidate := prefix + strconv.Itoa(k)
+			if rest != "" {
+				candidate += "." + rest
+			}
+			if rest != "" && !gjson.GetBytes(p.document, candidate).Exists() {
+				continue
+			}
+			if matches == index {
+				return candidate
+			}
+			matches++
 		}
+		return path
+	}
+	return path + "." + strconv.Itoa(index)
+}
 
-		// Expand all array's and nested arrays into separate metrics
-		nodes, err := p.expandArray(mNode)
-		if err != nil {
-			return nil, err
-		}
+// tagSourcePath adds the path the metric was gathered from as tag, if tagging the source path is enabled
+func tagSourcePath(m telegraf.Metric, c DataSet, path string) {
+	if c.SourcePathTag != "" && path != "" {
+		m.AddTag(c.SourcePathTag, displayPath(path))
+	}
+}
 
-		var m []telegraf.Metric
-		for _, n := range nodes {
-			m = append(m, n.Metric)
+// displayPath formats a GJSON path like JSONPath, with the array indices in brackets, e.g. "servers[2].load"
+func displayPath(path string) string {
+	var b strings.Builder
+	for i, part := range splitPath(path) {
+		if _, err := strconv.Atoi(part); err == nil {
+			b.WriteString("[" + part + "]")
+			continue
 		}
-		metrics = append(metrics, m)
+		if i > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteString(strings.ReplaceAll(part, "\\", ""))
 	}
+	return b.String()
+}
 
-	for i := 1; i < len(metrics); i++ {
-		metrics[i] = cartesianProduct(metrics[i-1], metrics[i])
+func resolveNegativeIndices(input []byte, path string) string {
+	if !strings.Contains(path, "-") {
+		return path
 	}
 
-	return metrics[len(metrics)-1], nil
+	parts := splitPath(path)
+	for i := 0; i < len(parts); i++ {
+		idx, err := strconv.Atoi(parts[i])
+		if err != nil || idx >= 0 {
+			continue
+		}
+		// A leading index is applied to the document itself
+		prefix := gjson.ParseBytes(input)
+		if i > 0 {
+			prefix = gjson.GetBytes(input, strings.Join(parts[:i], "."))
+		}
+		if !prefix.IsArray() {
+			continue
+		}
+		// An index out of range is kept out of range, so the query won't match anything
+		idx += len(prefix.Array())
+		if idx < 0 {
+			idx = len(prefix.Array())
+		}
+		parts[i] = strconv.Itoa(idx)
+	}
+
+	return strings.Join(parts, ".")
 }
 
 func cartesianProduct(a, b []telegraf.Metric) []telegraf.Metric {
+	return product(a, b, false)
+}
+
+// product combines every metric of a with every metric of b, the tags of b overwrite the tags of a unless keepTags
+// is set
+func product(a, b []telegraf.Metric, keepTags bool) []telegraf.Metric {
 	if len(a) == 0 {
 		return b
 	}
@@ -210,7 +2958,7 @@ func cartesianProduct(a, b []telegraf.Metric) []telegraf.Metric {
 	for _, a := range a {
 		for _, b := range b {
 			m := a.Copy()
-			mergeMetric(b, m)
+			mergeMetric(b, m, keepTags)
 			p[i] = m
 			i++
 		}
@@ -219,11 +2967,14 @@ func cartesianProduct(a, b []telegraf.Metric) []telegraf.Metric {
 	return p
 }
 
-func mergeMetric(a telegraf.Metric, m telegraf.Metric) {
+func mergeMetric(a telegraf.Metric, m telegraf.Metric, keepTags bool) {
 	for _, f := range a.FieldList() {
 		m.AddField(f.Key, f.Value)
 	}
 	for _, t := range a.TagList() {
+		if keepTags && m.HasTag(t.Key) {
+			continue
+		}
 		m.AddTag(t.Key, t.Value)
 	}
 }
@@ -234,6 +2985,9 @@ func (p *Parser) expandArray(result MetricNode) ([]MetricNode, error) {
 
 	if result.IsObject() {
 		if !p.iterateObjects {
+			if p.StrictObjects {
+				return nil, errObjectMatch
+			}
 			p.Log.Debugf("Found object in query ignoring it please use 'object' to gather metrics from objects")
 			return results, nil
 		}
@@ -247,6 +3001,7 @@ func (p *Parser) expandArray(result MetricNode) ([]MetricNode, error) {
 
 	if result.IsArray() {
 		var err error
+		var index int
 		result.ForEach(func(_, val gjson.Result) bool {
 			m := metric.New(
 				p.measurementName,
@@ -254,6 +3009,18 @@ func (p *Parser) expandArray(result MetricNode) ([]MetricNode, error) {
 				map[string]interface{}{},
 				p.Timestamp,
 			)
+			if p.sampleEveryN > 1 && index%p.sampleEveryN != 0 {
+				index++
+				return true
+			}
+			if p.indexTag != "" {
+				m.AddTag(p.indexTag, strconv.Itoa(index))
+			}
+			var sourcePath string
+			if result.sourcePath != "" {
+				sourcePath = p.elementPath(result.sourcePath, index)
+			}
+			index++
 
 			if val.IsObject() {
 				if p.iterateObjects {
@@ -269,12 +3036,15 @@ func (p *Parser) expandArray(result MetricNode) ([]MetricNode, error) {
 					}
 
 					results = append(results, r...)
+				} else if p.StrictObjects {
+					err = errObjectMatch
+					return false
 				} else {
 					p.Log.Debugf("Found object in query ignoring it please use 'object' to gather metrics from objects")
 				}
 				if len(results) != 0 {
 					for _, newResult := range results {
-						mergeMetric(result.Metric, newResult.Metric)
+						mergeMetric(result.Metric, newResult.Metric, false)
 					}
 				}
 				return true
@@ -289,10 +3059,12 @@ func (p *Parser) expandArray(result MetricNode) ([]MetricNode, error) {
 			n := MetricNode{
 				Tag:         result.Tag,
 				DesiredType: result.DesiredType,
+				Settings:    result.Settings,
 				OutputName:  result.OutputName,
 				SetName:     result.SetName,
 				Metric:      m,
 				Result:      val,
+				sourcePath:  sourcePath,
 			}
 			var r []MetricNode
 			r, err = p.expandArray(n)
@@ -307,31 +3079,25 @@ func (p *Parser) expandArray(result MetricNode) ([]MetricNode, error) {
 		}
 	} else {
 		if result.SetName == p.currentSettings.TimestampKey {
-			if p.currentSettings.TimestampFormat == "" {
+			formats := timestampFormats(p.currentSettings.TimestampFormat, p.currentSettings.TimestampFormats)
+			if len(formats) == 0 {
 				err := fmt.Errorf("use of 'timestamp_query' requires 'timestamp_format'")
 				return nil, err
 			}
-			timestamp, err := internal.ParseTimestamp(p.currentSettings.TimestampFormat, result.Value(), p.currentSettings.TimestampTimezone)
+			timestamp, err := p.parseTimestamp(result.Result, formats, p.currentSettings.TimestampTimezone)
 			if err != nil {
 				return nil, err
 			}
 			result.Metric.SetTime(timestamp)
 		} else {
-			switch result.Value().(type) {
-			case nil: // Ignore JSON values that are set as null
+			switch {
+			case result.Value() == nil: // Ignore JSON values that are set as null
+			case result.Settings.EmptyStringAsNull && result.Type == gjson.String && result.Str == "": // Same for empty strings if requested
 			default:
-				if result.Tag {
-					result.DesiredType = "string"
-				}
-				v, err := p.convertType(result.Value(), result.DesiredType, result.SetName)
-				if err != nil {
+				if err := p.addValue(result); err != nil {
 					return nil, err
 				}
-				if result.Tag {
-					result.Metric.AddTag(result.OutputName, v.(string))
-				} else {
-					result.Metric.AddField(result.OutputName, v)
-				}
+				tagSourcePath(result.Metric, result.Settings, result.sourcePath)
 			}
 		}
 
@@ -341,6 +3107,261 @@ func (p *Parser) expandArray(result MetricNode) ([]MetricNode, error) {
 	return results, nil
 }
 
+// pivots returns true if a metric is created for every key of an object returned by the path
+func (d *DataSet) pivots() bool {
+	return d.KeyTag != "" || len(d.KeySplitTags) != 0
+}
+
+// pivotObject creates a metric for every key of the object, with the key as tag and the value as field
+// With key_split the key is split into parts that are added as tags named by key_split_tags
+func (p *Parser) pivotObject(name string, result gjson.Result, c DataSet, sourcePath string) ([]telegraf.Metric, error) {
+	var metrics []telegraf.Metric
+	var err error
+	result.ForEach(func(key, value gjson.Result) bool {
+		if value.IsObject() || value.IsArray() {
+			p.Log.Debugf("Skipping key '%s' of '%s' as its value is not a scalar", key.String(), c.Path)
+			return true
+		}
+		m := metric.New(
+			p.measurementName,
+			map[string]string{},
+			map[string]interface{}{},
+			p.Timestamp,
+		)
+		if c.KeyTag != "" {
+			m.AddTag(c.KeyTag, key.String())
+		}
+		if len(c.KeySplitTags) != 0 {
+			// Parts beyond the tag names are ignored, tags without a part are omitted
+			parts := strings.Split(key.String(), c.KeySplit)
+			for i, part := range parts {
+				if i < len(c.KeySplitTags) && part != "" {
+					m.AddTag(c.KeySplitTags[i], part)
+				}
+			}
+		}
+		err = p.addValue(MetricNode{
+			OutputName:  name,
+			SetName:     name,
+			DesiredType: c.Type,
+			Settings:    c,
+			Metric:      m,
+			Result:      value,
+		})
+		if err != nil {
+			return false
+		}
+		if len(m.FieldList()) != 0 {
+			if sourcePath != "" {
+				tagSourcePath(m, c, sourcePath+"."+escapePathKey(key.String()))
+			}
+			metrics = append(metrics, m)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return metrics, nil
+}
+
+// zipColumns creates a metric for every position of the arrays held by the keys of the object, with a field per key
+// named by the key. Arrays shorter than the longest array don't add a field to the metrics of the missing positions.
+func (p *Parser) zipColumns(result gjson.Result, c DataSet) ([]telegraf.Metric, error) {
+	var metrics []telegraf.Metric
+	var err error
+	result.ForEach(func(key, column gjson.Result) bool {
+		name := key.String()
+		if c.keyFilter != nil && !c.keyFilter.Match(name) {
+			return true
+		}
+		if !column.IsArray() {
+			p.Log.Debugf("Skipping key '%s' of '%s' as its value is not an array", name, c.Path)
+			return true
+		}
+		for index, value := range column.Array() {
+			if index == len(metrics) {
+				m := metric.New(
+					p.measurementName,
+					map[string]string{},
+					map[string]interface{}{},
+					p.Timestamp,
+				)
+				if p.indexTag != "" {
+					m.AddTag(p.indexTag, strconv.Itoa(index))
+				}
+				metrics = append(metrics, m)
+			}
+			if value.IsObject() || value.IsArray() || value.Type == gjson.Null {
+				continue
+			}
+			err = p.addValue(MetricNode{
+				OutputName:  name,
+				SetName:     name,
+				DesiredType: c.Type,
+				Settings:    c,
+				Metric:      metrics[index],
+				Result:      value,
+			})
+			if err != nil {
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Drop positions without any field and apply the sampling like for any other array
+	kept := metrics[:0]
+	for index, m := range metrics {
+		if p.sampleEveryN > 1 && index%p.sampleEveryN != 0 {
+			continue
+		}
+		if len(m.FieldList()) != 0 {
+			kept = append(kept, m)
+		}
+	}
+	return kept, nil
+}
+
+// addValue will convert the value of the node and add it as field or tag to the metric of the node
+// Values that can't be converted are skipped unless the parser is strict
+func (p *Parser) addValue(result MetricNode) error {
+	raw := result.String()
+	if result.Tag {
+		result.DesiredType = "string"
+	} else if len(result.Settings.Bitfields) != 0 {
+		return p.addBitfields(result)
+	}
+
+	// Canonicalize strings before mapping them, so "US " and "us" are mapped alike
+	if result.Type == gjson.String && (result.Settings.TrimSpace || result.Settings.Lowercase) {
+		s := result.Str
+		if result.Settings.TrimSpace {
+			s = strings.TrimSpace(s)
+		}
+		if result.Settings.Lowercase {
+			s = strings.ToLower(s)
+		}
+		result.Result = gjson.Result{Type: gjson.String, Str: s, Raw: strconv.Quote(s)}
+	}
+
+	// Replace sentinel values like "N/A" before converting them, mapping to an empty string skips the value
+	if mapped, ok := result.Settings.ValueMap[result.String()]; ok {
+		if mapped == "" {
+			p.Log.Debugf("Skipping value %q of '%s' mapped to an empty string", result.String(), result.SetName)
+			return nil
+		}
+		result.Result = gjson.Result{Type: gjson.String, Str: mapped, Raw: strconv.Quote(mapped)}
+	}
+
+	// Map enumerations like log levels to numbers, values missing in the map use the default value or are skipped
+	if len(result.Settings.EnumMap) != 0 {
+		n, ok := result.Settings.EnumMap[result.String()]
+		switch {
+		case ok:
+			result.Result = gjson.Result{Type: gjson.Number, Num: float64(n), Raw: strconv.FormatInt(n, 10)}
+		case result.Settings.DefaultValue != "":
+			d := result.Settings.DefaultValue
+			result.Result = gjson.Result{Type: gjson.String, Str: d, Raw: strconv.Quote(d)}
+		default:
+			p.Log.Debugf("Skipping value %q of '%s' missing in the enum map", result.String(), result.SetName)
+			return nil
+		}
+		if result.DesiredType == "" && !p.ForceFloat {
+			result.DesiredType = "int"
+		}
+	}
+
+	// Tags with a type are converted to it before formatting them, so e.g. "01" and 1.0 become the same tag "1" for "int"
+	desiredType := result.DesiredType
+	if result.Tag && result.Settings.Type != "" {
+		desiredType = result.Settings.Type
+	}
+	v, err := p.convertValue(p.value(result.Result, desiredType), desiredType, result.SetName, result.Settings)
+	if err == nil && result.Tag {
+		v, err = internal.ToString(v)
+	}
+	if err != nil {
+		if p.Strict {
+			return err
+		}
+		p.Log.Debugf("Skipping value: %v", err)
+		return nil
+	}
+
+	if wlog.LogLevel() == wlog.DEBUG {
+		p.logExtraction(result, raw, v)
+	}
+
+	if result.Tag {
+		result.Metric.AddTag(result.OutputName, v.(string))
+	} else {
+		result.Metric.AddField(result.OutputName, v)
+		if result.Settings.KeepRaw {
+			suffix := result.Settings.RawSuffix
+			if suffix == "" {
+				suffix = "_raw"
+			}
+			result.Metric.AddField(result.OutputName+suffix, raw)
+		}
+	}
+	return nil
+}
+
+// logExtraction logs the query, the matched value and the converted value of a field or tag, to diagnose the
+// configuration without calling Explain. It is only called at debug level as formatting the line is comparatively costly.
+func (p *Parser) logExtraction(result MetricNode, raw string, v interface{}) {
+	kind := "field"
+	if result.Tag {
+		kind = "tag"
+	}
+	query := result.Settings.Path
+	if query == "" {
+		query = result.SetName
+	}
+	p.Log.Debugf("Extracted measurement=%q %s=%q query=%q matched=%q value=%q type=%T", p.measurementName, kind, result.OutputName, query, raw, fmt.Sprint(v), v)
+}
+
+// value returns the value of the result, with type inference integer numbers without a desired type are an int64
+func (p *Parser) value(result gjson.Result, desiredType string) interface{} {
+	if p.TypeInference && !p.ForceFloat && desiredType == "" && result.Type == gjson.Number {
+		if i, err := strconv.ParseInt(result.Raw, 10, 64); err == nil {
+			return i
+		}
+	}
+	return result.Value()
+}
+
+// addBitfields will decode the integer value of the node into a boolean field for each configured bit
+func (p *Parser) addBitfields(result MetricNode) error {
+	v, err := p.convertType(result.Value(), "uint", result.SetName)
+	if err != nil {
+		if p.Strict {
+			return err
+		}
+		p.Log.Debugf("Skipping value: %v", err)
+		return nil
+	}
+	value := v.(uint64)
+
+	bits := make([]int, 0, len(result.Settings.Bitfields))
+	for bit := range result.Settings.Bitfields {
+		bits = append(bits, bit)
+	}
+	sort.Ints(bits)
+
+	for _, bit := range bits {
+		if bit < 0 || bit > 63 {
+			return fmt.Errorf("invalid bit %d for field '%s', must be between 0 and 63", bit, result.SetName)
+		}
+		result.Metric.AddField(result.Settings.Bitfields[bit], value&(1<<uint(bit)) != 0)
+	}
+	return nil
+}
+
 // processObjects will iterate over all 'object' configs and create metrics for each
 func (p *Parser) processObjects(objects []JSONObject, input []byte) ([]telegraf.Metric, error) {
 	p.iterateObjects = true
@@ -350,7 +3371,7 @@ func (p *Parser) processObjects(objects []JSONObject, input []byte) ([]telegraf.
 		if c.Path == "" {
 			return nil, fmt.Errorf("GJSON path is required")
 		}
-		result := gjson.GetBytes(input, c.Path)
+		result := p.getPath(input, c.Path)
 
 		if result.Type == gjson.Null {
 			return nil, fmt.Errorf("GJSON Path returned null")
@@ -367,7 +3388,7 @@ func (p *Parser) processObjects(objects []JSONObject, input []byte) ([]telegraf.
 		}
 		metrics, err := p.expandArray(rootObject)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("measurement '%s', object (path '%s'): %w", p.measurementName, c.Path, err)
 		}
 		for _, m := range metrics {
 			t = append(t, m.Metric)
@@ -389,9 +3410,9 @@ func (p *Parser) combineObject(result MetricNode) ([]MetricNode, error) {
 			// Determine if field/tag set name is configured
 			var setName string
 			if result.SetName != "" {
-				setName = result.SetName + "_" + strings.ReplaceAll(key.String(), " ", "_")
+				setName = result.SetName + "_" + p.keyName(key)
 			} else {
-				setName = strings.ReplaceAll(key.String(), " ", "_")
+				setName = p.keyName(key)
 			}
 
 			if p.isExcluded(setName) || !p.isIncluded(setName, val) {
@@ -400,7 +3421,7 @@ func (p *Parser) combineObject(result MetricNode) ([]MetricNode, error) {
 
 			var outputName string
 			if p.currentSettings.DisablePrependKeys {
-				outputName = strings.ReplaceAll(key.String(), " ", "_")
+				outputName = p.keyName(key)
 			} else {
 				outputName = setName
 			}
@@ -453,7 +3474,7 @@ func (p *Parser) combineObject(result MetricNode) ([]MetricNode, error) {
 						// If another non-array element was found, merge it into all previous gathered metrics
 						if len(results) != 0 {
 							for _, newResult := range results {
-								mergeMetric(result.Metric, newResult.Metric)
+								mergeMetric(result.Metric, newResult.Metric, false)
 							}
 						}
 					} else {
@@ -516,14 +3537,238 @@ func (p *Parser) isExcluded(key string) bool {
 	return false
 }
 
+// ParseLine parses a single JSON document into a metric
+// As a document can expand into multiple metrics, an error is returned instead of dropping all but one of them
 func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
-	return nil, fmt.Errorf("ParseLine is designed for parsing influx line protocol, therefore not implemented for parsing JSON")
+	if strings.TrimSpace(strings.TrimPrefix(line, string(utf8BOM))) == "" {
+		return nil, ErrEmptyInput
+	}
+
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(metrics) {
+	case 0:
+		return nil, nil
+	case 1:
+		return metrics[0], nil
+	default:
+		return nil, fmt.Errorf("line expanded to %d metrics, use Parse instead", len(metrics))
+	}
 }
 
 func (p *Parser) SetDefaultTags(tags map[string]string) {
 	p.DefaultTags = tags
 }
 
+// convertValue will apply the settings of a 'field' or 'tag' config to the value before converting it to the desired type
+func (p *Parser) convertValue(input interface{}, desiredType string, name string, settings DataSet) (interface{}, error) {
+	if s, ok := input.(string); ok && desiredType == "bool" {
+		for _, v := range settings.TrueValues {
+			if strings.EqualFold(s, v) {
+				return true, nil
+			}
+		}
+		for _, v := range settings.FalseValues {
+			if strings.EqualFold(s, v) {
+				return false, nil
+			}
+		}
+	}
+
+	if desiredType == "bool" && !settings.StrictBool {
+		// Any number other than zero is true
+		switch v := input.(type) {
+		case float64:
+			return v != 0, nil
+		case int64:
+			return v != 0, nil
+		}
+	}
+
+	if s, ok := input.(string); ok && (desiredType == "int" || desiredType == "uint" || desiredType == "float") {
+		input = normalizeNumber(s, settings.DecimalSeparator, settings.GroupSeparator)
+	}
+
+	v, err := p.convertType(input, desiredType, name)
+	var overflow *overflowError
+	if errors.As(err, &overflow) && settings.Overflow == "clamp" {
+		p.Log.Debugf("Clamping value: %v", err)
+		v, err = overflow.clamp(), nil
+	}
+	if err != nil {
+		return v, err
+	}
+	if p.ForceFloat && desiredType != "int" && desiredType != "uint" {
+		v = toFloat(v)
+	}
+	if settings.Scale == 0 && settings.Offset == 0 {
+		return v, nil
+	}
+	return scaleValue(v, name, settings)
+}
+
+// toFloat turns integers into floats for ForceFloat, other values are returned as they are
+func toFloat(v interface{}) interface{} {
+	switch n := v.(type) {
+	case int64:
+		return float64(n)
+	case uint64:
+		return float64(n)
+	}
+	return v
+}
+
+// scaleValue multiplies a numeric value with the scale and adds the offset, the result is always a float
+func scaleValue(input interface{}, name string, settings DataSet) (interface{}, error) {
+	scale := settings.Scale
+	if scale == 0 {
+		scale = 1
+	}
+
+	var v float64
+	switch value := input.(type) {
+	case float64:
+		v = value
+	case int:
+		v = float64(value)
+	case int64:
+		v = float64(value)
+	case uint64:
+		v = float64(value)
+	default:
+		return nil, fmt.Errorf("Unable to scale field '%s' of type %T, only numbers can be scaled", name, input)
+	}
+	return v*scale + settings.Offset, nil
+}
+
+// normalizeNumber removes the group separators from a locale formatted number and replaces the decimal separator
+// with a dot, so "1.234,56" with the decimal separator "," and the group separator "." results in "1234.56"
+func normalizeNumber(s string, decimalSeparator string, groupSeparator string) string {
+	if groupSeparator != "" {
+		s = strings.ReplaceAll(s, groupSeparator, "")
+	}
+	if decimalSeparator != "" && decimalSeparator != "." {
+		if strings.Contains(s, ".") {
+			// A dot is neither a group nor the decimal separator, keep the value malformed
+			return s
+		}
+		s = strings.ReplaceAll(s, decimalSeparator, ".")
+	}
+	return s
+}
+
+// hasBasePrefix checks if the string is an integer with a 0x, 0o or 0b prefix
+// A leading zero alone is not considered a prefix, so "010" is still parsed as decimal
+func hasBasePrefix(s string) bool {
+	s = strings.TrimLeft(s, "+-")
+	if len(s) < 3 || s[0] != '0' {
+		return false
+	}
+	switch s[1] {
+	case 'x', 'X', 'o', 'O', 'b', 'B':
+		return true
+	}
+	return false
+}
+
+// parseInt parses decimal integers, integers with a base prefix like "0x1F" and whole numbers in
+// scientific notation like "1.2e3"
+func parseInt(s string) (int64, error) {
+	if hasBasePrefix(s) {
+		return strconv.ParseInt(s, 0, 64)
+	}
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err != nil && strings.ContainsAny(s, "eE") {
+		f, ferr := strconv.ParseFloat(s, 64)
+		if ferr == nil && f == math.Trunc(f) {
+			if f < math.MinInt64 || f >= math.MaxInt64 {
+				return 0, &strconv.NumError{Func: "ParseInt", Num: s, Err: strconv.ErrRange}
+			}
+			return int64(f), nil
+		}
+	}
+	return i, err
+}
+
+// parseUint is the unsigned variant of parseInt
+func parseUint(s string) (uint64, error) {
+	if hasBasePrefix(s) {
+		return strconv.ParseUint(s, 0, 64)
+	}
+	u, err := strconv.ParseUint(s, 10, 64)
+	if err != nil && strings.ContainsAny(s, "eE") {
+		f, ferr := strconv.ParseFloat(s, 64)
+		if ferr == nil && f == math.Trunc(f) {
+			if f < 0 || f >= math.MaxUint64 {
+				return 0, &strconv.NumError{Func: "ParseUint", Num: s, Err: strconv.ErrRange}
+			}
+			return uint64(f), nil
+		}
+	}
+	if err != nil && strings.HasPrefix(s, "-") {
+		// ParseUint reports a syntax error for negative numbers, those are out of range instead
+		if i, ierr := parseInt(s); ierr == nil && i == 0 {
+			return 0, nil
+		} else if ierr == nil || errors.Is(ierr, strconv.ErrRange) {
+			return 0, &strconv.NumError{Func: "ParseUint", Num: s, Err: strconv.ErrRange}
+		}
+	}
+	return u, err
+}
+
+// parseBool parses the strings "true", "t" and "1" as true and "false", "f" and "0" as false, regardless of the
+// capitalization. Other values like "yes" or "" are no bool, unless configured by true_values or false_values
+func parseBool(s string) (bool, bool) {
+	switch strings.ToLower(s) {
+	case "true", "t", "1":
+		return true, true
+	case "false", "f", "0":
+		return false, true
+	}
+	return false, false
+}
+
+// overflowError is returned if a number is out of the range of the desired integer type
+type overflowError struct {
+	name        string
+	desiredType string
+	value       string
+	negative    bool
+}
+
+func (e *overflowError) Error() string {
+	return fmt.Sprintf("Unable to convert field '%s' to type %s: value %s is out of range", e.name, e.desiredType, e.value)
+}
+
+// clamp returns the maximum or minimum value of the desired type, depending on the sign of the value
+func (e *overflowError) clamp() interface{} {
+	switch {
+	case e.desiredType == "uint" && e.negative:
+		return uint64(0)
+	case e.desiredType == "uint":
+		return uint64(math.MaxUint64)
+	case e.negative:
+		return int64(math.MinInt64)
+	}
+	return int64(math.MaxInt64)
+}
+
+// rangeError returns an overflowError if err is caused by a value out of range, otherwise the conversion error
+func rangeError(err error, name string, desiredType string, value string) error {
+	if errors.Is(err, strconv.ErrRange) {
+		return &overflowError{name: name, desiredType: desiredType, value: value, negative: strings.HasPrefix(value, "-")}
+	}
+	return fmt.Errorf("Unable to convert field '%s' to type %s: %v", name, desiredType, err)
+}
+
+// floatRangeError returns the overflowError for a float out of the range of the desired integer type
+func floatRangeError(f float64, name string, desiredType string) error {
+	return &overflowError{name: name, desiredType: desiredType, value: strconv.FormatFloat(f, 'g', -1, 64), negative: f < 0}
+}
+
 // convertType will convert the value parsed from the input JSON to the specified type in the config
 func (p *Parser) convertType(input interface{}, desiredType string, name string) (interface{}, error) {
 	switch inputType := input.(type) {
@@ -531,31 +3776,57 @@ func (p *Parser) convertType(input interface{}, desiredType string, name string)
 		if desiredType != "string" {
 			switch desiredType {
 			case "uint":
-				r, err := strconv.ParseUint(inputType, 10, 64)
+				r, err := parseUint(inputType)
 				if err != nil {
-					return nil, fmt.Errorf("Unable to convert field '%s' to type uint: %v", name, err)
+					return nil, rangeError(err, name, desiredType, inputType)
 				}
 				return r, nil
 			case "int":
-				r, err := strconv.Atoi(inputType)
+				r, err := parseInt(inputType)
 				if err != nil {
-					return nil, fmt.Errorf("Unable to convert field '%s' to type int: %v", name, err)
+					return nil, rangeError(err, name, desiredType, inputType)
 				}
-				return r, nil
+				return int(r), nil
 			case "float":
 				r, err := strconv.ParseFloat(inputType, 64)
+				if err != nil && hasBasePrefix(inputType) {
+					i, ierr := strconv.ParseInt(inputType, 0, 64)
+					if ierr == nil {
+						return float64(i), nil
+					}
+				}
 				if err != nil {
 					return nil, fmt.Errorf("Unable to convert field '%s' to type float: %v", name, err)
 				}
 				return r, nil
 			case "bool":
-				r, err := strconv.ParseBool(inputType)
-				if err != nil {
-					return nil, fmt.Errorf("Unable to convert field '%s' to type bool: %v", name, err)
+				r, ok := parseBool(inputType)
+				if !ok {
+					return nil, fmt.Errorf("Unable to convert field '%s' to type bool: invalid value %q", name, inputType)
 				}
 				return r, nil
 			}
 		}
+	case int64:
+		switch desiredType {
+		case "string":
+			return strconv.FormatInt(inputType, 10), nil
+		case "float":
+			return float64(inputType), nil
+		case "uint":
+			if inputType < 0 {
+				return nil, &overflowError{name: name, desiredType: desiredType, value: strconv.FormatInt(inputType, 10), negative: true}
+			}
+			return uint64(inputType), nil
+		case "bool":
+			switch inputType {
+			case 0:
+				return false, nil
+			case 1:
+				return true, nil
+			}
+			return nil, fmt.Errorf("Unable to convert field '%s' to type bool", name)
+		}
 	case bool:
 		switch desiredType {
 		case "string":
@@ -572,15 +3843,29 @@ func (p *Parser) convertType(input interface{}, desiredType string, name string)
 			}
 
 			return uint64(0), nil
+		case "float":
+			if inputType {
+				return float64(1), nil
+			}
+
+			return float64(0), nil
 		}
 	case float64:
 		if desiredType != "float" {
 			switch desiredType {
 			case "string":
-				return fmt.Sprint(inputType), nil
+				// Use the minimal number of digits without exponent, so 1 and 1.0 result in the same tag value
+				return strconv.FormatFloat(inputType, 'f', -1, 64), nil
 			case "int":
+				// The float of math.MaxInt64 is rounded up to 2^63, which is already out of range
+				if inputType < math.MinInt64 || inputType >= math.MaxInt64 {
+					return nil, floatRangeError(inputType, name, desiredType)
+				}
 				return int64(inputType), nil
 			case "uint":
+				if inputType <= -1 || inputType >= math.MaxUint64 {
+					return nil, floatRangeError(inputType, name, desiredType)
+				}
 				return uint64(inputType), nil
 			case "bool":
 				if inputType == 0 {
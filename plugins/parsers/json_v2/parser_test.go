@@ -2,18 +2,33 @@ package json_v2_test
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
 	"io/ioutil"
+	"log"
+	"math"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/config"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/inputs/file"
+	"github.com/influxdata/telegraf/plugins/parsers"
 	"github.com/influxdata/telegraf/plugins/parsers/influx"
+	"github.com/influxdata/telegraf/plugins/parsers/json_v2"
+	influxSerializer "github.com/influxdata/telegraf/plugins/serializers/influx"
 	"github.com/influxdata/telegraf/testutil"
+	"github.com/influxdata/wlog"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+	"github.com/tinylib/msgp/msgp"
 )
 
 func TestData(t *testing.T) {
@@ -77,6 +92,210 @@ func TestData(t *testing.T) {
 			name: "Test field with null",
 			test: "null",
 		},
+		{
+			name: "Test negative array index",
+			test: "negative_index",
+		},
+		{
+			name: "Test filters",
+			test: "filter",
+		},
+		{
+			name: "Test requiring monotonic values",
+			test: "require_monotonic",
+		},
+		{
+			name: "Test total count field",
+			test: "total_count_field",
+		},
+		{
+			name: "Test flattening objects",
+			test: "flatten",
+		},
+		{
+			name: "Test unwrapping documents",
+			test: "unwrap",
+		},
+		{
+			name: "Test all fields as strings",
+			test: "all_fields_as_strings",
+		},
+		{
+			name: "Test custom bool values",
+			test: "bool_values",
+		},
+		{
+			name: "Test match coverage",
+			test: "match_coverage",
+		},
+		{
+			name: "Test keeping raw JSON",
+			test: "raw_json",
+		},
+		{
+			name: "Test decoding bitfields",
+			test: "bitfields",
+		},
+		{
+			name: "Test expiry tag",
+			test: "expiry_tag",
+		},
+		{
+			name: "Test lookup",
+			test: "lookup",
+		},
+		{
+			name: "Test fallback paths",
+			test: "fallback_paths",
+		},
+		{
+			name: "Test locale formatted numbers",
+			test: "locale_numbers",
+		},
+		{
+			name: "Test stringified nested JSON",
+			test: "nested_json",
+		},
+		{
+			name: "Test normalized tag values",
+			test: "tag_normalization",
+		},
+		{
+			name: "Test scale and offset",
+			test: "scale",
+		},
+		{
+			name: "Test joining arrays",
+			test: "join_array",
+		},
+		{
+			name: "Test index tag",
+			test: "index_tag",
+		},
+		{
+			name: "Test include and exclude keys of flattened objects",
+			test: "flatten_filter",
+		},
+		{
+			name: "Test numeric strings in scientific notation and with base prefixes",
+			test: "numeric_strings",
+		},
+		{
+			name: "Test default tags of a configuration",
+			test: "config_default_tags",
+		},
+		{
+			name: "Test numbers converted to bool",
+			test: "numeric_bool",
+		},
+		{
+			name: "Test sub format",
+			test: "sub_format",
+		},
+		{
+			name: "Test required tags",
+			test: "required_tag",
+		},
+		{
+			name: "Test root path",
+			test: "root_path",
+		},
+		{
+			name: "Test key tag",
+			test: "key_tag",
+		},
+		{
+			name: "Test source path tag",
+			test: "source_path",
+		},
+		{
+			name: "Test measurement name template",
+			test: "measurement_template",
+		},
+		{
+			name: "Test matrix rows",
+			test: "matrix",
+		},
+		{
+			name: "Test trim space",
+			test: "trim_space",
+		},
+		{
+			name: "Test field conditions",
+			test: "condition",
+		},
+		{
+			name: "Test split",
+			test: "split",
+		},
+		{
+			name: "Test sample every n",
+			test: "sample_every_n",
+		},
+		{
+			name: "Test key split",
+			test: "key_split",
+		},
+		{
+			name: "Test emit count",
+			test: "emit_count",
+		},
+		{
+			name: "Test empty string as null",
+			test: "empty_string_as_null",
+		},
+		{
+			name: "Test value map",
+			test: "value_map",
+		},
+		{
+			name: "Test groups",
+			test: "groups",
+		},
+		{
+			name: "Test tag value map",
+			test: "tag_value_map",
+		},
+		{
+			name: "Test keeping the raw string as companion field",
+			test: "keep_raw",
+		},
+		{
+			name: "Test columnar objects of arrays",
+			test: "columnar",
+		},
+		{
+			name: "Test tag precedence of queries with the same name",
+			test: "tag_precedence",
+		},
+		{
+			name: "Test aggregating arrays of numbers",
+			test: "aggregate",
+		},
+		{
+			name: "Test mapping enumerations to numbers",
+			test: "enum_map",
+		},
+		{
+			name: "Test expanding histogram buckets",
+			test: "histogram_buckets",
+		},
+		{
+			name: "Test converting tag values to a type",
+			test: "tag_types",
+		},
+		{
+			name: "Test combining array queries",
+			test: "array_queries",
+		},
+		{
+			name: "Test parsing JSON lines",
+			test: "json_lines",
+		},
+		{
+			name: "Test skipping an object matched by a field path",
+			test: "object_match",
+		},
 	}
 
 	for _, tc := range tests {
@@ -109,30 +328,2529 @@ func TestData(t *testing.T) {
 	}
 }
 
-func readMetricFile(path string) ([]telegraf.Metric, error) {
-	var metrics []telegraf.Metric
-	expectedFile, err := os.Open(path)
-	if err != nil {
-		return metrics, err
+func TestEventLog(t *testing.T) {
+	// Run the whole pipeline from the configuration file and also check the
+	// event time, which TestData ignores
+	buf, err := ioutil.ReadFile("testdata/event_log/telegraf.conf")
+	require.NoError(t, err)
+	inputs.Add("file", func() telegraf.Input {
+		return &file.File{}
+	})
+	cfg := config.NewConfig()
+	require.NoError(t, cfg.LoadConfigData(buf))
+
+	acc := testutil.Accumulator{}
+	for _, i := range cfg.Inputs {
+		require.NoError(t, i.Init())
+		require.NoError(t, i.Gather(&acc))
 	}
-	defer expectedFile.Close()
 
-	parser := influx.NewParser(influx.NewMetricHandler())
-	scanner := bufio.NewScanner(expectedFile)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if line != "" {
-			m, err := parser.ParseLine(line)
-			if err != nil {
-				return nil, fmt.Errorf("unable to parse metric in %q failed: %v", line, err)
+	expected, err := readMetricFile("testdata/event_log/expected.out")
+	require.NoError(t, err)
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+	require.Equal(t, time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), acc.GetTelegrafMetrics()[0].Time().UTC())
+}
+
+func TestTimePrecision(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 123456789, time.UTC)
+	parser := &json_v2.Parser{
+		Configs: []json_v2.Config{
+			{
+				MeasurementName: "event",
+				TimestampPath:   "time",
+				TimestampFormat: "unix_ns",
+				Fields: []json_v2.DataSet{
+					{Path: "value"},
+				},
+			},
+			{
+				MeasurementName: "received",
+				Fields: []json_v2.DataSet{
+					{Path: "value"},
+				},
+			},
+		},
+		TimePrecision: time.Second,
+		TimeFunc:      func() time.Time { return now },
+		Log:           testutil.Logger{},
+	}
+
+	actual, err := parser.Parse([]byte(`{"time":1609459200987654321,"value":1}`))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("event", map[string]string{}, map[string]interface{}{"value": float64(1)}, time.Unix(1609459200, 0)),
+		testutil.MustMetric("received", map[string]string{}, map[string]interface{}{"value": float64(1)}, time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestTimestampFractionalSeconds(t *testing.T) {
+	parser := &json_v2.Parser{
+		Configs: []json_v2.Config{
+			{
+				MeasurementName: "event",
+				TimestampPath:   "time",
+				TimestampFormat: "unix",
+				Fields: []json_v2.DataSet{
+					{Path: "value"},
+				},
+			},
+		},
+		Log: testutil.Logger{},
+	}
+
+	tests := []struct {
+		input    string
+		expected time.Time
+	}{
+		{input: `{"time":1609459200.5,"value":1}`, expected: time.Unix(1609459200, 500000000)},
+		{input: `{"time":1609459200.123,"value":1}`, expected: time.Unix(1609459200, 123000000)},
+		{input: `{"time":1609459200,"value":1}`, expected: time.Unix(1609459200, 0)},
+		{input: `{"time":"1609459200.25","value":1}`, expected: time.Unix(1609459200, 250000000)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			metrics, err := parser.Parse([]byte(tt.input))
+			require.NoError(t, err)
+			require.Len(t, metrics, 1)
+			require.Equal(t, tt.expected.UTC(), metrics[0].Time())
+		})
+	}
+}
+
+func TestMeasurementNamePath(t *testing.T) {
+	parser := &json_v2.Parser{
+		Configs: []json_v2.Config{
+			{
+				MeasurementName:     "device",
+				MeasurementNamePath: "$.device.model",
+				Fields: []json_v2.DataSet{
+					{Path: "value"},
+				},
+			},
+		},
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, parser.Init())
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{input: `{"device":{"model":"th100"},"value":1}`, expected: "th100"},
+		{input: `{"device":{"model":42},"value":1}`, expected: "42"},
+		{input: `{"device":{},"value":1}`, expected: "device"},
+		{input: `{"device":{"model":null},"value":1}`, expected: "device"},
+		{input: `{"device":{"model":""},"value":1}`, expected: "device"},
+		{input: `{"device":{"model":{"name":"th100"}},"value":1}`, expected: "device"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			metrics, err := parser.Parse([]byte(tt.input))
+			require.NoError(t, err)
+			require.Len(t, metrics, 1)
+			require.Equal(t, tt.expected, metrics[0].Name())
+		})
+	}
+}
+
+func TestParseConcurrent(t *testing.T) {
+	parser := &json_v2.Parser{
+		Configs: []json_v2.Config{
+			{
+				MeasurementNamePath: "name",
+				TimestampPath:       "time",
+				TimestampFormat:     "unix",
+				IndexTag:            "index",
+				Fields: []json_v2.DataSet{
+					{Path: "values"},
+				},
+				JSONObjects: []json_v2.JSONObject{
+					{Path: "devices", Tags: []string{"id"}},
+				},
+			},
+		},
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, parser.Init())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				name := fmt.Sprintf("worker%d", i)
+				input := fmt.Sprintf(`{"name":%q,"time":%d,"values":[1,2],"devices":[{"id":"a","load":%d}]}`, name, i, j)
+				metrics, err := parser.Parse([]byte(input))
+				if !assert.NoError(t, err) || !assert.Len(t, metrics, 4) {
+					return
+				}
+				for _, m := range metrics {
+					assert.Equal(t, name, m.Name())
+					assert.Equal(t, time.Unix(int64(i), 0).UTC(), m.Time())
+				}
+				assert.Equal(t, float64(j), metrics[2].Fields()["load"])
+				assert.Equal(t, float64(j), metrics[3].Fields()["load"])
 			}
-			metrics = append(metrics, m)
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestTimestampUnixAuto(t *testing.T) {
+	parser := &json_v2.Parser{
+		Configs: []json_v2.Config{
+			{
+				MeasurementName: "event",
+				TimestampPath:   "time",
+				TimestampFormat: "unix_auto",
+				Fields: []json_v2.DataSet{
+					{Path: "value"},
+				},
+			},
+		},
+		Log: testutil.Logger{},
+	}
+
+	for _, input := range []string{`{"time":1609459200,"value":1}`, `{"time":1609459200000,"value":1}`} {
+		metrics, err := parser.Parse([]byte(input))
+		require.NoError(t, err)
+		require.Len(t, metrics, 1)
+		require.Equal(t, time.Unix(1609459200, 0).UTC(), metrics[0].Time(), input)
+	}
+}
+
+func TestTimestampRFC3339Variants(t *testing.T) {
+	parser := &json_v2.Parser{
+		Configs: []json_v2.Config{
+			{
+				MeasurementName: "event",
+				TimestampPath:   "time",
+				TimestampFormat: "rfc3339",
+				Fields: []json_v2.DataSet{
+					{Path: "value"},
+				},
+			},
+		},
+		Strict: true,
+		Log:    testutil.Logger{},
+	}
+
+	tests := []struct {
+		input    string
+		expected time.Time
+	}{
+		{input: "2021-01-01T00:00:00Z", expected: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{input: "2021-01-01T00:00:00.123Z", expected: time.Date(2021, 1, 1, 0, 0, 0, 123000000, time.UTC)},
+		{input: "2021-01-01T00:00:00.123456789Z", expected: time.Date(2021, 1, 1, 0, 0, 0, 123456789, time.UTC)},
+		{input: "2021-01-01T00:00:00+00:00", expected: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{input: "2021-01-01T00:00:00+05:30", expected: time.Date(2020, 12, 31, 18, 30, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			metrics, err := parser.Parse([]byte(fmt.Sprintf(`{"time":%q,"value":1}`, tt.input)))
+			require.NoError(t, err)
+			require.Len(t, metrics, 1)
+			require.True(t, tt.expected.Equal(metrics[0].Time()), "expected %v but got %v", tt.expected, metrics[0].Time())
+		})
+	}
+}
+
+func TestTimestampFormats(t *testing.T) {
+	parser := &json_v2.Parser{
+		Configs: []json_v2.Config{
+			{
+				MeasurementName:  "event",
+				TimestampPath:    "time",
+				TimestampFormats: []string{"2006-01-02 15:04:05", "unix_ms", "rfc3339"},
+				Fields: []json_v2.DataSet{
+					{Path: "value"},
+				},
+			},
+		},
+		Strict: true,
+		Log:    testutil.Logger{},
+	}
+
+	tests := []struct {
+		input    string
+		expected time.Time
+	}{
+		{input: `"2021-01-01 00:00:00"`, expected: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{input: `1609459200123`, expected: time.Date(2021, 1, 1, 0, 0, 0, 123000000, time.UTC)},
+		{input: `"2021-01-01T00:00:00+05:30"`, expected: time.Date(2020, 12, 31, 18, 30, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			metrics, err := parser.Parse([]byte(fmt.Sprintf(`{"time":%s,"value":1}`, tt.input)))
+			require.NoError(t, err)
+			require.Len(t, metrics, 1)
+			require.True(t, tt.expected.Equal(metrics[0].Time()), "expected %v but got %v", tt.expected, metrics[0].Time())
+		})
+	}
+
+	// The single format is tried first
+	parser.Configs[0].TimestampFormat = "unix"
+	metrics, err := parser.Parse([]byte(`{"time":1609459200,"value":1}`))
+	require.NoError(t, err)
+	require.Equal(t, time.Unix(1609459200, 0).UTC(), metrics[0].Time())
+
+	_, err = parser.Parse([]byte(`{"time":"yesterday","value":1}`))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `timestamp "yesterday" matches none of the formats`)
+}
+
+func TestTimestampTimezone(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   string
+		timezone string
+		input    string
+		expected time.Time
+	}{
+		{
+			name:     "layout without zone defaults to UTC",
+			format:   "2006-01-02 15:04:05",
+			input:    `"2021-01-01 12:00:00"`,
+			expected: time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "layout without zone",
+			format:   "2006-01-02 15:04:05",
+			timezone: "America/New_York",
+			input:    `"2021-01-01 12:00:00"`,
+			expected: time.Date(2021, 1, 1, 17, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "zone of the input takes precedence",
+			format:   "rfc3339",
+			timezone: "America/New_York",
+			input:    `"2021-01-01T12:00:00+01:00"`,
+			expected: time.Date(2021, 1, 1, 11, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "unix time is independent of the zone",
+			format:   "unix_ms",
+			timezone: "America/New_York",
+			input:    `1609502400000`,
+			expected: time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := &json_v2.Parser{
+				Configs: []json_v2.Config{
+					{
+						MeasurementName:   "event",
+						TimestampPath:     "device.time",
+						TimestampFormat:   tt.format,
+						TimestampTimezone: tt.timezone,
+						Fields: []json_v2.DataSet{
+							{Path: "device.value"},
+						},
+					},
+				},
+				Log: testutil.Logger{},
+			}
+			require.NoError(t, parser.Init())
+
+			metrics, err := parser.Parse([]byte(fmt.Sprintf(`{"device":{"time":%s,"value":1}}`, tt.input)))
+			require.NoError(t, err)
+			require.Len(t, metrics, 1)
+			require.True(t, tt.expected.Equal(metrics[0].Time()), "expected %v but got %v", tt.expected, metrics[0].Time())
+		})
+	}
+}
+
+func TestTimestampEpochString(t *testing.T) {
+	parser := &json_v2.Parser{
+		Configs: []json_v2.Config{
+			{
+				MeasurementName: "event",
+				TimestampPath:   "time",
+				TimestampFormat: "unix_ms",
+				Fields: []json_v2.DataSet{
+					{Path: "value"},
+				},
+			},
+		},
+		TimeFunc: func() time.Time { return time.Unix(0, 0) },
+		Strict:   true,
+		Log:      testutil.Logger{},
+	}
+
+	for _, input := range []string{`{"time":"1609459200000","value":1}`, `{"time":" 1609459200000 ","value":1}`, `{"time":"1.6094592e12","value":1}`} {
+		metrics, err := parser.Parse([]byte(input))
+		require.NoError(t, err)
+		require.Len(t, metrics, 1)
+		require.Equal(t, time.Unix(1609459200, 0).UTC(), metrics[0].Time(), input)
+	}
+
+	_, err := parser.Parse([]byte(`{"time":"yesterday","value":1}`))
+	require.EqualError(t, err, `measurement 'event', timestamp (path 'time'): timestamp "yesterday" is not a number as required by format 'unix_ms'`)
+
+	parser.Strict = false
+	metrics, err := parser.Parse([]byte(`{"time":"yesterday","value":1}`))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	require.Equal(t, time.Unix(0, 0), metrics[0].Time())
+}
+
+func TestMatrixRows(t *testing.T) {
+	parser := &json_v2.Parser{
+		Configs: []json_v2.Config{
+			{
+				MeasurementName: "up",
+				RootPath:        "data.result[0].values",
+				TimestampPath:   "[0]",
+				TimestampFormat: "unix",
+				Fields: []json_v2.DataSet{
+					{Path: "[1]", Rename: "value", Type: "float"},
+				},
+			},
+		},
+		Log: testutil.Logger{},
+	}
+
+	metrics, err := parser.Parse([]byte(`{"data":{"result":[{"values":[[1609459200,"1.5"],[1609459260,"2.0"]]}]}}`))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("up", map[string]string{}, map[string]interface{}{"value": 1.5}, time.Unix(1609459200, 0)),
+		testutil.MustMetric("up", map[string]string{}, map[string]interface{}{"value": 2.0}, time.Unix(1609459260, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestStableFieldOrder(t *testing.T) {
+	parser := &json_v2.Parser{
+		Configs: []json_v2.Config{
+			{
+				MeasurementName: "request",
+				Fields: []json_v2.DataSet{
+					{Path: "status"},
+					{Path: "latency"},
+					{Path: "message", SubFormat: "logfmt"},
+					{Path: "counters", Flatten: true},
+				},
+			},
+		},
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, parser.Init())
+
+	input := []byte(`{"status":200,"latency":1.5,"message":"z=1 y=2 x=3 w=4 v=5 u=6","counters":{"c":1,"b":2,"a":3}}`)
+	serialize := func() string {
+		metrics, err := parser.Parse(input)
+		require.NoError(t, err)
+		for _, m := range metrics {
+			m.SetTime(time.Unix(0, 0))
 		}
+		return lineProtocol(t, metrics)
 	}
-	err = expectedFile.Close()
-	if err != nil {
-		return metrics, err
+
+	expected := serialize()
+	require.Equal(t, "request status=200,latency=1.5,message_u=6i,message_v=5i,message_w=4i,message_x=3i,message_y=2i,message_z=1i,counters_c=1,counters_b=2,counters_a=3 0\n", expected)
+	for i := 0; i < 100; i++ {
+		require.Equal(t, expected, serialize())
 	}
+}
 
-	return metrics, nil
+func TestInvalidUTF8(t *testing.T) {
+	parser := &json_v2.Parser{
+		Configs: []json_v2.Config{
+			{
+				MeasurementName: "feed",
+				Tags: []json_v2.DataSet{
+					{Path: "city"},
+				},
+				Fields: []json_v2.DataSet{
+					{Path: "title"},
+				},
+			},
+		},
+		InvalidUTF8:          "replace",
+		UnicodeNormalization: "NFC",
+		Log:                  testutil.Logger{},
+	}
+	require.NoError(t, parser.Init())
+
+	// The city is written with a combining accent, the title contains an invalid byte
+	input := []byte("{\"city\":\"Mont\u0065\u0301limar\",\"title\":\"caf\xe9 au lait\"}")
+	metrics, err := parser.Parse(input)
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	require.Equal(t, map[string]string{"city": "Mont\u00e9limar"}, metrics[0].Tags())
+	require.Equal(t, map[string]interface{}{"title": "caf\uFFFD au lait"}, metrics[0].Fields())
+
+	parser.InvalidUTF8 = "drop"
+	metrics, err = parser.Parse(input)
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	require.Equal(t, map[string]interface{}{"title": "caf au lait"}, metrics[0].Fields())
+
+	parser.InvalidUTF8 = "ignore"
+	require.EqualError(t, parser.Init(), "invalid invalid_utf8 mode 'ignore', must be 'replace' or 'drop'")
+}
+
+func TestTrimSpace(t *testing.T) {
+	parser := &json_v2.Parser{
+		Configs: []json_v2.Config{
+			{
+				MeasurementName: "device",
+				JSONObjects: []json_v2.JSONObject{
+					{Path: "@this", Tags: []string{"zone"}},
+				},
+			},
+		},
+		TrimSpace: true,
+		Log:       testutil.Logger{},
+	}
+	require.NoError(t, parser.Init())
+
+	// Several keys and values need trimming, not only the first ones, and keys are trimmed before spaces within them
+	// are replaced
+	input := []byte(`{"\ta":1,"b":" y ","c":"z"," d e ":" v "," zone ":" eu "}`)
+	metrics, err := parser.Parse(input)
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	require.Equal(t, map[string]string{"zone": "eu"}, metrics[0].Tags())
+	expected := map[string]interface{}{"a": 1.0, "b": "y", "c": "z", "d_e": "v"}
+	require.Equal(t, expected, metrics[0].Fields())
+}
+
+func TestParseWithTime(t *testing.T) {
+	parser := &json_v2.Parser{
+		Configs: []json_v2.Config{
+			{
+				MeasurementName: "request",
+				TimestampPath:   "time",
+				TimestampFormat: "unix",
+				Fields: []json_v2.DataSet{
+					{Path: "value"},
+				},
+			},
+		},
+		TimeFunc: func() time.Time { return time.Unix(0, 0) },
+		Log:      testutil.Logger{},
+	}
+
+	received := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	metrics, err := parser.ParseWithTime([]byte(`{"value":[1,2]}`), received)
+	require.NoError(t, err)
+	require.Len(t, metrics, 2)
+	for _, m := range metrics {
+		require.Equal(t, received, m.Time())
+	}
+
+	metrics, err = parser.ParseWithTime([]byte(`{"time":1609459200,"value":1}`), received)
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	require.Equal(t, time.Unix(1609459200, 0).UTC(), metrics[0].Time())
+
+	metrics, err = parser.Parse([]byte(`{"value":1}`))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	require.Equal(t, time.Unix(0, 0), metrics[0].Time())
+}
+
+func TestParseChunked(t *testing.T) {
+	parser := &json_v2.Parser{
+		Configs: []json_v2.Config{
+			{
+				MeasurementName: "chunked",
+				Fields: []json_v2.DataSet{
+					{Path: "values", Rename: "value"},
+				},
+			},
+		},
+		Log: testutil.Logger{},
+	}
+
+	var chunkSizes []int
+	var total int
+	err := parser.ParseChunked([]byte(`{"values":[1,2,3,4,5]}`), 2, func(metrics []telegraf.Metric) error {
+		chunkSizes = append(chunkSizes, len(metrics))
+		total += len(metrics)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []int{2, 2, 1}, chunkSizes)
+	require.Equal(t, 5, total)
+
+	err = parser.ParseChunked([]byte(`{"values":[1]}`), 0, func(metrics []telegraf.Metric) error {
+		return nil
+	})
+	require.Error(t, err)
+
+	// The batches hold the same metrics as Parse, including the limit of the number of metrics
+	parser.Configs[0].Fields = []json_v2.DataSet{{Path: "#.value", Rename: "value"}}
+	parser.MaxMetrics = 2
+	input := []byte(`[{"value":1},{"value":2},{"value":3},{"value":4}]`)
+	_, expectedErr := parser.Parse(input)
+	require.Error(t, expectedErr)
+	err = parser.ParseChunked(input, 2, func(metrics []telegraf.Metric) error {
+		return nil
+	})
+	require.EqualError(t, err, expectedErr.Error())
+	parser.MaxMetrics = 0
+
+	// JSON lines are batched across the lines
+	parser.Configs[0].Fields = []json_v2.DataSet{{Path: "value"}}
+	parser.JSONLines = true
+	chunkSizes = nil
+	err = parser.ParseChunked([]byte("{\"value\":1}\n{\"value\":2}\n\n{\"value\":3}\n"), 2, func(metrics []telegraf.Metric) error {
+		chunkSizes = append(chunkSizes, len(metrics))
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []int{2, 1}, chunkSizes)
+}
+
+func TestParseLine(t *testing.T) {
+	parser := &json_v2.Parser{
+		Configs: []json_v2.Config{
+			{
+				MeasurementName: "line",
+				Fields: []json_v2.DataSet{
+					{Path: "values", Rename: "value"},
+				},
+			},
+		},
+		Log: testutil.Logger{},
+	}
+
+	m, err := parser.ParseLine(`{"values":1}`)
+	require.NoError(t, err)
+	require.NotNil(t, m)
+	v, ok := m.GetField("value")
+	require.True(t, ok)
+	require.Equal(t, float64(1), v)
+
+	m, err = parser.ParseLine(`{"values":[1,2,3]}`)
+	require.EqualError(t, err, "line expanded to 3 metrics, use Parse instead")
+	require.Nil(t, m)
+}
+
+func TestEmptyInput(t *testing.T) {
+	parser := &json_v2.Parser{
+		Configs: []json_v2.Config{
+			{
+				MeasurementName: "line",
+				Fields: []json_v2.DataSet{
+					{Path: "value"},
+				},
+			},
+		},
+		Log: testutil.Logger{},
+	}
+
+	for _, input := range []string{"", " \t ", "\n", "\xef\xbb\xbf"} {
+		t.Run(fmt.Sprintf("%q", input), func(t *testing.T) {
+			metrics, err := parser.Parse([]byte(input))
+			require.NoError(t, err)
+			require.Empty(t, metrics)
+
+			m, err := parser.ParseLine(input)
+			require.ErrorIs(t, err, json_v2.ErrEmptyInput)
+			require.Nil(t, m)
+		})
+	}
+
+	// Invalid input is still an error different from the empty input
+	_, err := parser.ParseLine("{")
+	require.Error(t, err)
+	require.NotErrorIs(t, err, json_v2.ErrEmptyInput)
+}
+
+func TestByteOrderMark(t *testing.T) {
+	parser := &json_v2.Parser{
+		Configs: []json_v2.Config{
+			{
+				MeasurementName: "export",
+				Fields:          []json_v2.DataSet{{Path: "value"}},
+			},
+		},
+		ParseErrorsMeasurement: "parse_errors",
+		Log:                    testutil.Logger{},
+	}
+	require.NoError(t, parser.Init())
+	expected := []telegraf.Metric{
+		testutil.MustMetric("export", map[string]string{}, map[string]interface{}{"value": 1.5}, time.Unix(0, 0)),
+	}
+
+	for _, input := range []string{"\xef\xbb\xbf{\"value\":1.5}", "\xef\xbb\xbf \n{\"value\":1.5}", "{\"value\":1.5}"} {
+		t.Run(fmt.Sprintf("%q", input), func(t *testing.T) {
+			actual, err := parser.Parse([]byte(input))
+			require.NoError(t, err)
+			testutil.RequireMetricsEqual(t, expected, actual, testutil.IgnoreTime())
+
+			actual, err = parser.ParseReader(strings.NewReader(input), nil)
+			require.NoError(t, err)
+			testutil.RequireMetricsEqual(t, expected, actual, testutil.IgnoreTime())
+		})
+	}
+
+	m, err := parser.ParseLine("\xef\xbb\xbf{\"value\":1.5}")
+	require.NoError(t, err)
+	testutil.RequireMetricsEqual(t, expected, []telegraf.Metric{m}, testutil.IgnoreTime())
+
+	// Only a leading byte order mark is removed
+	actual, err := parser.Parse([]byte("{\"value\":1.5}\xef\xbb\xbf"))
+	require.NoError(t, err)
+	require.Len(t, actual, 1)
+	require.Equal(t, "parse_errors", actual[0].Name())
+}
+
+func TestDuplicateFieldNames(t *testing.T) {
+	parser := &json_v2.Parser{
+		Configs: []json_v2.Config{
+			{
+				MeasurementName: "duplicate",
+				Fields: []json_v2.DataSet{
+					{Path: "metrics.cpu"},
+					{Path: "stats.cpu_pct", Rename: "cpu"},
+				},
+			},
+		},
+		Log: testutil.Logger{},
+	}
+	require.EqualError(t, parser.Init(), "duplicate field name 'cpu' in configuration for measurement 'duplicate'")
+
+	// The same name can be used in separate configurations, as they result in separate metrics
+	parser = &json_v2.Parser{
+		Configs: []json_v2.Config{
+			{
+				MeasurementName: "new",
+				Fields: []json_v2.DataSet{
+					{Path: "metrics.cpu"},
+				},
+			},
+			{
+				MeasurementName: "old",
+				Fields: []json_v2.DataSet{
+					{Path: "stats.cpu_pct", Rename: "cpu"},
+				},
+			},
+		},
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, parser.Init())
+}
+
+func TestTimestampPerConfig(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	parser := &json_v2.Parser{
+		Configs: []json_v2.Config{
+			{
+				MeasurementName: "event",
+				TimestampPath:   "event.time",
+				TimestampFormat: "unix_ms",
+				Fields: []json_v2.DataSet{
+					{Path: "event.value"},
+				},
+			},
+			{
+				MeasurementName: "batch",
+				TimestampPath:   "batch.created",
+				TimestampFormat: "2006-01-02T15:04:05Z07:00",
+				Fields: []json_v2.DataSet{
+					{Path: "batch.size"},
+				},
+			},
+			{
+				MeasurementName: "received",
+				Fields: []json_v2.DataSet{
+					{Path: "batch.size"},
+				},
+			},
+		},
+		TimeFunc: func() time.Time { return now },
+		Log:      testutil.Logger{},
+	}
+
+	input := []byte(`{"event":{"time":1609459200500,"value":1},"batch":{"created":"2021-01-02T00:00:00Z","size":10}}`)
+	actual, err := parser.Parse(input)
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("event", map[string]string{}, map[string]interface{}{"value": float64(1)}, time.Unix(1609459200, 500*1e6)),
+		testutil.MustMetric("batch", map[string]string{}, map[string]interface{}{"size": float64(10)}, time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC)),
+		testutil.MustMetric("received", map[string]string{}, map[string]interface{}{"size": float64(10)}, now),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestTimestampDefault(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	parser := &json_v2.Parser{
+		Configs: []json_v2.Config{
+			{
+				MeasurementName:  "event",
+				TimestampPath:    "event.time",
+				TimestampFormat:  "2006-01-02T15:04:05Z07:00",
+				TimestampDefault: "2021-01-01T00:00:00Z",
+				Fields: []json_v2.DataSet{
+					{Path: "event.value"},
+				},
+			},
+			{
+				MeasurementName: "batch",
+				TimestampPath:   "batch.created",
+				TimestampFormat: "unix",
+				Fields: []json_v2.DataSet{
+					{Path: "batch.size"},
+				},
+			},
+		},
+		TimeFunc: func() time.Time { return now },
+		Log:      testutil.Logger{},
+	}
+	require.NoError(t, parser.Init())
+
+	tests := []struct {
+		name      string
+		input     string
+		eventTime time.Time
+	}{
+		{
+			name:      "both resolved",
+			input:     `{"event":{"time":"2021-01-02T00:00:00Z","value":1},"batch":{"created":1609632000,"size":10}}`,
+			eventTime: time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "missing",
+			input:     `{"event":{"value":1},"batch":{"created":1609632000,"size":10}}`,
+			eventTime: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "malformed",
+			input:     `{"event":{"time":"yesterday","value":1},"batch":{"created":1609632000,"size":10}}`,
+			eventTime: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := parser.Parse([]byte(tt.input))
+			require.NoError(t, err)
+
+			expected := []telegraf.Metric{
+				testutil.MustMetric("event", map[string]string{}, map[string]interface{}{"value": float64(1)}, tt.eventTime),
+				testutil.MustMetric("batch", map[string]string{}, map[string]interface{}{"size": float64(10)}, time.Unix(1609632000, 0)),
+			}
+			testutil.RequireMetricsEqual(t, expected, actual)
+		})
+	}
+
+	// A parser used without Init parses the default on its own
+	uninitialized := &json_v2.Parser{
+		Configs: []json_v2.Config{
+			{
+				MeasurementName:  "event",
+				TimestampPath:    "event.time",
+				TimestampFormat:  "2006-01-02T15:04:05Z07:00",
+				TimestampDefault: "2021-01-03T00:00:00Z",
+				Fields: []json_v2.DataSet{
+					{Path: "event.value"},
+				},
+			},
+		},
+		TimeFunc: func() time.Time { return now },
+		Log:      testutil.Logger{},
+	}
+	actual, err := uninitialized.Parse([]byte(`{"event":{"value":1}}`))
+	require.NoError(t, err)
+	require.Len(t, actual, 1)
+	require.Equal(t, time.Date(2021, 1, 3, 0, 0, 0, 0, time.UTC), actual[0].Time())
+
+	// Without a default the malformed timestamp fails the input
+	parser.Configs[0].TimestampDefault = ""
+	_, err = parser.Parse([]byte(`{"event":{"time":"yesterday","value":1},"batch":{"created":1609632000,"size":10}}`))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "measurement 'event', timestamp (path 'event.time')")
+}
+
+func TestTimestampDefaultInvalid(t *testing.T) {
+	parser := &json_v2.Parser{
+		Configs: []json_v2.Config{{MeasurementName: "event", TimestampDefault: "0"}},
+		Log:     testutil.Logger{},
+	}
+	require.EqualError(t, parser.Init(), "timestamp_default of measurement 'event' requires 'timestamp_format'")
+
+	parser.Configs[0].TimestampFormat = "unix"
+	parser.Configs[0].TimestampDefault = "never"
+	err := parser.Init()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid timestamp_default of measurement 'event'")
+}
+
+func TestErrorContext(t *testing.T) {
+	parser := &json_v2.Parser{
+		Configs: []json_v2.Config{
+			{
+				MeasurementName: "servers",
+				Fields: []json_v2.DataSet{
+					{Path: "servers.0.load", Type: "int"},
+				},
+			},
+		},
+		Strict: true,
+		Log:    testutil.Logger{},
+	}
+
+	_, err := parser.Parse([]byte(`{"servers":[{"load":"n/a"}]}`))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "measurement 'servers', field 'load' (path 'servers.0.load')")
+	require.Contains(t, err.Error(), "Unable to convert field 'load' to type int")
+}
+
+func TestExplain(t *testing.T) {
+	parser := &json_v2.Parser{
+		Configs: []json_v2.Config{
+			{
+				MeasurementName: "explain",
+				Fields: []json_v2.DataSet{
+					{Path: "load", Type: "int"},
+					{Path: "missing"},
+					{Path: "values", Type: "float"},
+				},
+				Tags: []json_v2.DataSet{
+					{Path: "host"},
+				},
+			},
+		},
+		Log: testutil.Logger{},
+	}
+
+	explanation, err := parser.Explain([]byte(`{"host":"server01","load":"n/a","values":[1,"2.5"]}`))
+	require.NoError(t, err)
+
+	expected := map[string]interface{}{
+		"json_v2[0].field.load": map[string]interface{}{
+			"path":    "load",
+			"matched": true,
+			"raw":     `"n/a"`,
+			"error":   `Unable to convert field 'load' to type int: strconv.ParseInt: parsing "n/a": invalid syntax`,
+		},
+		"json_v2[0].field.missing": map[string]interface{}{
+			"path":    "missing",
+			"matched": false,
+		},
+		"json_v2[0].field.values": map[string]interface{}{
+			"path":    "values",
+			"matched": true,
+			"raw":     `[1,"2.5"]`,
+			"value":   []interface{}{float64(1), float64(2.5)},
+		},
+		"json_v2[0].tag.host": map[string]interface{}{
+			"path":    "host",
+			"matched": true,
+			"raw":     `"server01"`,
+			"value":   "server01",
+		},
+	}
+	require.Equal(t, expected, explanation)
+}
+
+func TestPaths(t *testing.T) {
+	parser := &json_v2.Parser{Log: testutil.Logger{}}
+
+	input := []byte(`{"region":"eu","servers":[{"name":"a","load":0.5},{"name":"b","load":1.5,"tags":[]}],"meta.version":2}`)
+	paths, err := parser.Paths(input)
+	require.NoError(t, err)
+	expected := []string{
+		"region",
+		"servers[0].name",
+		"servers[0].load",
+		"servers[1].name",
+		"servers[1].load",
+		"servers[1].tags",
+		`meta\.version`,
+	}
+	require.Equal(t, expected, paths)
+
+	// The paths can be used as they are in a configuration
+	parser.Configs = []json_v2.Config{
+		{
+			MeasurementName: "paths",
+			Fields: []json_v2.DataSet{
+				{Path: "servers[1].load", Rename: "load"},
+				{Path: `meta\.version`, Rename: "version", Type: "int"},
+			},
+		},
+	}
+	require.NoError(t, parser.Init())
+	metrics, err := parser.Parse(input)
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	require.Equal(t, map[string]interface{}{"load": 1.5, "version": int64(2)}, metrics[0].Fields())
+
+	_, err = parser.Paths([]byte(`{"broken":`))
+	require.Error(t, err)
+}
+
+func TestSeriesHash(t *testing.T) {
+	input := []byte(`{"flows":[{"src":"10.0.0.1","dst":"10.0.0.2","port":443,"bytes":120},{"src":"10.0.0.2","dst":"10.0.0.1","port":443,"bytes":80}]}`)
+
+	tests := []struct {
+		algorithm string
+		expected  []string
+	}{
+		{algorithm: "", expected: []string{"6809e9505018f369", "8608dfa69fec5f8f"}},
+		{algorithm: "fnv", expected: []string{"6809e9505018f369", "8608dfa69fec5f8f"}},
+		{algorithm: "xxhash", expected: []string{"a766eb91bc757fb1", "41d589497cf6aecd"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			parser := &json_v2.Parser{
+				Configs: []json_v2.Config{
+					{
+						MeasurementName: "flows",
+						HashTag:         "flow_id",
+						HashKeys:        []string{"src", "dst", "port"},
+						HashAlgorithm:   tt.algorithm,
+						JSONObjects: []json_v2.JSONObject{
+							{Path: "flows", Tags: []string{"src", "dst"}},
+						},
+					},
+				},
+				Log: testutil.Logger{},
+			}
+			require.NoError(t, parser.Init())
+
+			// The same input always results in the same identifier, replacing the hashed tags
+			for run := 0; run < 2; run++ {
+				metrics, err := parser.Parse(input)
+				require.NoError(t, err)
+				require.Len(t, metrics, 2)
+				for i, m := range metrics {
+					require.Equal(t, map[string]string{"flow_id": tt.expected[i]}, m.Tags())
+					require.Equal(t, 443.0, m.Fields()["port"])
+				}
+			}
+		})
+	}
+
+	parser := &json_v2.Parser{
+		Configs: []json_v2.Config{{MeasurementName: "flows", HashTag: "flow_id"}},
+		Log:     testutil.Logger{},
+	}
+	require.EqualError(t, parser.Init(), "hash_tag and hash_keys of measurement 'flows' must be set together")
+}
+
+func TestValidateAgainst(t *testing.T) {
+	parser := &json_v2.Parser{
+		Configs: []json_v2.Config{
+			{
+				MeasurementName: "servers",
+				Fields: []json_v2.DataSet{
+					{Path: "load"},
+					{Path: "stats.uptme", Rename: "uptime"},
+					{Path: "errors", DefaultValue: "0", Type: "int"},
+				},
+				Tags: []json_v2.DataSet{
+					{Path: "host"},
+				},
+			},
+		},
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, parser.Init())
+
+	sample := []byte(`{"host":"server01","load":0.5,"stats":{"uptime":3600}}`)
+	err := parser.ValidateAgainst(sample)
+	require.EqualError(t, err, "1 of the queries failed: json_v2[0] (measurement 'servers'), field 'uptime' (path 'stats.uptme'): doesn't match any value")
+
+	parser.Configs[0].Fields[1].Path = "stats.uptime"
+	require.NoError(t, parser.ValidateAgainst(sample))
+
+	// Values that can't be converted fail the validation as well
+	parser.Configs[0].Fields[0].Type = "int"
+	err = parser.ValidateAgainst([]byte(`{"host":"server01","load":"n/a","stats":{"uptime":3600}}`))
+	require.EqualError(t, err, "1 of the queries failed: json_v2[0] (measurement 'servers'), field 'load' (path 'load'): Unable to convert field 'load' to type int: strconv.ParseInt: parsing \"n/a\": invalid syntax")
+}
+
+func TestDedup(t *testing.T) {
+	parser := &json_v2.Parser{
+		Configs: []json_v2.Config{
+			{
+				MeasurementName: "temperature",
+				TimestampPath:   "time",
+				TimestampFormat: "unix",
+				Tags: []json_v2.DataSet{
+					{Path: "room"},
+				},
+				Fields: []json_v2.DataSet{
+					{Path: "value"},
+				},
+			},
+		},
+		Dedup: true,
+		Log:   testutil.Logger{},
+	}
+
+	input := `{"room":"kitchen","value":20.5,"time":1609459200}` + "\n" +
+		`{"room":"office","value":19.0,"time":1609459200}` + "\n" +
+		`{"room":"kitchen","value":21.0,"time":1609459260}` + "\n" +
+		`{"room":"office","value":18.5,"time":1609459100}` + "\n"
+	metrics, err := parser.ParseReader(strings.NewReader(input), nil)
+	require.NoError(t, err)
+
+	// The kitchen is updated by a later reading, the late office reading is older and dropped
+	expected := []telegraf.Metric{
+		testutil.MustMetric("temperature", map[string]string{"room": "kitchen"}, map[string]interface{}{"value": 21.0}, time.Unix(1609459260, 0)),
+		testutil.MustMetric("temperature", map[string]string{"room": "office"}, map[string]interface{}{"value": 19.0}, time.Unix(1609459200, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+
+	// Without timestamps the later record in the input is kept
+	parser.Configs[0].TimestampPath = ""
+	input = `{"room":"kitchen","value":20.5}` + "\n" + `{"room":"kitchen","value":21.0}` + "\n"
+	metrics, err = parser.ParseReader(strings.NewReader(input), nil)
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	require.Equal(t, map[string]interface{}{"value": 21.0}, metrics[0].Fields())
+}
+
+func TestParseReader(t *testing.T) {
+	parser := &json_v2.Parser{
+		Configs: []json_v2.Config{
+			{
+				MeasurementName: "log",
+				Fields: []json_v2.DataSet{
+					{Path: "message"},
+				},
+				Tags: []json_v2.DataSet{
+					{Path: "level"},
+				},
+			},
+		},
+		Log: testutil.Logger{},
+	}
+
+	long := strings.Repeat("x", 100*1024)
+	input := `{"level":"info","message":"started"}` + "\n\n" +
+		`{"level":"debug","message":"` + long + `"}` + "\n"
+
+	_, err := parser.ParseReader(strings.NewReader(input), nil)
+	require.ErrorIs(t, err, bufio.ErrTooLong)
+
+	parser.MaxLineSize = 1024 * 1024
+	metrics, err := parser.ParseReader(strings.NewReader(input), nil)
+	require.NoError(t, err)
+	require.Len(t, metrics, 2)
+	require.Equal(t, map[string]string{"level": "info"}, metrics[0].Tags())
+	require.Equal(t, "started", metrics[0].Fields()["message"])
+	require.Equal(t, map[string]string{"level": "debug"}, metrics[1].Tags())
+	require.Equal(t, long, metrics[1].Fields()["message"])
+}
+
+func TestJSONLines(t *testing.T) {
+	parser := &json_v2.Parser{
+		Configs: []json_v2.Config{
+			{
+				MeasurementName: "log",
+				Fields: []json_v2.DataSet{
+					{Path: "message"},
+				},
+				Tags: []json_v2.DataSet{
+					{Path: "level"},
+				},
+			},
+		},
+		JSONLines: true,
+		Log:       testutil.Logger{},
+	}
+	require.NoError(t, parser.Init())
+
+	// Lines longer than the default line size of ParseReader are fine, as the input is already in memory
+	long := strings.Repeat("x", 100*1024)
+	input := "{\"level\":\"info\",\"message\":\"started\"}\r\n\n" +
+		`{"level":"debug","message":"` + long + `"}` + "\n" +
+		`{"level":"error","message":"stopped"}`
+	metrics, err := parser.Parse([]byte(input))
+	require.NoError(t, err)
+	require.Len(t, metrics, 3)
+	require.Equal(t, map[string]string{"level": "info"}, metrics[0].Tags())
+	require.Equal(t, long, metrics[1].Fields()["message"])
+	require.Equal(t, map[string]string{"level": "error"}, metrics[2].Tags())
+
+	input = `{"level":"info","message":"started"}` + "\n" + `{"level":` + "\n" + `{"level":"error","message":"stopped"}`
+	_, err = parser.Parse([]byte(input))
+	require.EqualError(t, err, "line 2: Invalid JSON provided, unable to parse")
+
+	parser.OnError = "skip"
+	metrics, err = parser.Parse([]byte(input))
+	require.EqualError(t, err, "skipped 1 of 3 lines with errors: line 2: Invalid JSON provided, unable to parse")
+	require.Len(t, metrics, 2)
+
+	parser.Format = "msgpack"
+	require.EqualError(t, parser.Init(), "json_lines requires the format 'json', not 'msgpack'")
+}
+
+func TestParseErrorsMeasurement(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	parser := &json_v2.Parser{
+		Configs: []json_v2.Config{
+			{
+				MeasurementName: "log",
+				Fields: []json_v2.DataSet{
+					{Path: "message"},
+				},
+			},
+		},
+		ParseErrorsMeasurement:   "parse_errors",
+		ParseErrorsSnippetLength: 10,
+		TimeFunc:                 func() time.Time { return now },
+		Log:                      testutil.Logger{},
+	}
+
+	input := `{"message":"started"` + "\n" + `{"message":"running"}` + "\n"
+	actual, err := parser.ParseReader(strings.NewReader(input), nil)
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("log", map[string]string{}, map[string]interface{}{"message": "running"}, now),
+		testutil.MustMetric("parse_errors", map[string]string{"snippet": `{"message"`}, map[string]interface{}{"count": int64(1)}, now),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+
+	parser.ParseErrorsMeasurement = ""
+	_, err = parser.ParseReader(strings.NewReader(input), nil)
+	require.EqualError(t, err, "line 1: Invalid JSON provided, unable to parse")
+}
+
+func TestRecursiveDescent(t *testing.T) {
+	parser := &json_v2.Parser{
+		Configs: []json_v2.Config{
+			{
+				MeasurementName: "order",
+				Fields: []json_v2.DataSet{
+					{Path: "$..price"},
+				},
+			},
+		},
+		Log: testutil.Logger{},
+	}
+
+	input := []byte(`{"items":[{"price":10,"parts":[{"price":2}]},{"name":"box"}],"shipping":{"price":5}}`)
+	metrics, err := parser.Parse(input)
+	require.NoError(t, err)
+	require.Len(t, metrics, 3)
+	for i, expected := range []float64{10, 2, 5} {
+		require.Equal(t, map[string]interface{}{"price": expected}, metrics[i].Fields())
+	}
+
+	parser.Configs[0].Fields[0].MaxMatches = 1
+	metrics, err = parser.Parse(input)
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	require.Equal(t, map[string]interface{}{"price": float64(10)}, metrics[0].Fields())
+
+	parser.Strict = true
+	_, err = parser.Parse(input)
+	require.EqualError(t, err, "measurement 'order', field 'price' (path '$..price'): found 3 matches, more than the maximum of 1")
+}
+
+func TestFilterExpressions(t *testing.T) {
+	input := []byte(`{
+		"items": [
+			{"name": "a", "status": "ok", "latency": 1},
+			{"name": "b", "status": "failed", "latency": 2, "error": "timeout"},
+			{"name": "c", "status": "ok", "latency": 3}
+		],
+		"region": {"items": [{"status": "ok", "latency": 4}]},
+		"counts": [1, 5, 9]
+	}`)
+
+	tests := []struct {
+		path     string
+		expected []float64
+	}{
+		{path: `$.items[?(@.status=="ok")].latency`, expected: []float64{1, 3}},
+		{path: `items[?(@.status == 'ok')].latency`, expected: []float64{1, 3}},
+		{path: `items[?(@.latency>1)].latency`, expected: []float64{2, 3}},
+		{path: `items[?(@.error)].latency`, expected: []float64{2}},
+		{path: `counts[?(@ >= 5)]`, expected: []float64{5, 9}},
+		{path: `$..items[?(@.status=='ok')].latency`, expected: []float64{1, 3, 4}},
+		{path: `items[?(@.status=="unknown")].latency`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			parser := &json_v2.Parser{
+				Configs: []json_v2.Config{
+					{
+						MeasurementName: "requests",
+						Fields: []json_v2.DataSet{
+							{Path: tt.path, Rename: "value"},
+						},
+					},
+				},
+				Log: testutil.Logger{},
+			}
+			require.NoError(t, parser.Init())
+
+			metrics, err := parser.Parse(input)
+			require.NoError(t, err)
+			require.Len(t, metrics, len(tt.expected))
+			for i, expected := range tt.expected {
+				require.Equal(t, map[string]interface{}{"value": expected}, metrics[i].Fields())
+			}
+		})
+	}
+}
+
+func TestFilterExpressionsInvalid(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{
+			path:     `items[?(@.status=="ok" && @.latency>1)].latency`,
+			expected: `json_v2[0] (measurement 'requests'), field 'latency': invalid path 'items[?(@.status=="ok" && @.latency>1)].latency': filter '@.status=="ok" && @.latency>1' combines conditions, which is not supported`,
+		},
+		{
+			path:     `items[?(status=="ok")].latency`,
+			expected: `json_v2[0] (measurement 'requests'), field 'latency': invalid path 'items[?(status=="ok")].latency': invalid filter 'status=="ok"', must compare '@' or '@.key' with a value`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			parser := &json_v2.Parser{
+				Configs: []json_v2.Config{
+					{
+						MeasurementName: "requests",
+						Fields:          []json_v2.DataSet{{Path: tt.path}},
+					},
+				},
+				Log: testutil.Logger{},
+			}
+			require.EqualError(t, parser.Init(), tt.expected)
+		})
+	}
+}
+
+func TestDefaultFieldName(t *testing.T) {
+	parser := &json_v2.Parser{
+		Configs: []json_v2.Config{
+			{
+				MeasurementName:  "reading",
+				DefaultFieldName: "level",
+				Fields: []json_v2.DataSet{
+					{Path: "@this"},
+				},
+			},
+		},
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, parser.Init())
+
+	metrics, err := parser.Parse([]byte(`[1,2,3]`))
+	require.NoError(t, err)
+	require.Len(t, metrics, 3)
+	for i, m := range metrics {
+		require.Equal(t, map[string]interface{}{"level": float64(i + 1)}, m.Fields())
+	}
+
+	parser.Configs[0].DefaultFieldName = ""
+	metrics, err = parser.Parse([]byte(`42`))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	require.Equal(t, map[string]interface{}{"value": float64(42)}, metrics[0].Fields())
+}
+
+func TestTypeInference(t *testing.T) {
+	parser := &json_v2.Parser{
+		Configs: []json_v2.Config{
+			{
+				MeasurementName: "inferred",
+				Fields: []json_v2.DataSet{
+					{Path: "bool"},
+					{Path: "int"},
+					{Path: "float"},
+					{Path: "exponent"},
+					{Path: "string"},
+					{Path: "numeric_string"},
+					{Path: "typed", Type: "float"},
+				},
+			},
+		},
+		Log: testutil.Logger{},
+	}
+
+	input := []byte(`{"bool":true,"int":3,"float":3.5,"exponent":3e2,"string":"x","numeric_string":"3","typed":3}`)
+	metrics, err := parser.Parse(input)
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	require.Equal(t, map[string]interface{}{
+		"bool":           true,
+		"int":            float64(3),
+		"float":          3.5,
+		"exponent":       float64(300),
+		"string":         "x",
+		"numeric_string": "3",
+		"typed":          float64(3),
+	}, metrics[0].Fields())
+
+	parser.TypeInference = true
+	metrics, err = parser.Parse(input)
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	require.Equal(t, map[string]interface{}{
+		"bool":           true,
+		"int":            int64(3),
+		"float":          3.5,
+		"exponent":       float64(300),
+		"string":         "x",
+		"numeric_string": "3",
+		"typed":          float64(3),
+	}, metrics[0].Fields())
+}
+
+func TestOnError(t *testing.T) {
+	parser := &json_v2.Parser{
+		Configs: []json_v2.Config{
+			{
+				MeasurementName: "order",
+				Fields: []json_v2.DataSet{
+					{Path: "amount", Type: "float"},
+				},
+				Tags: []json_v2.DataSet{
+					{Path: "id"},
+				},
+			},
+		},
+		Strict: true,
+		Log:    testutil.Logger{},
+	}
+	input := `{"id":"a","amount":10}` + "\n" + `{"id":"b","amount":"n/a"}` + "\n" + `{"id":"c","amount":30}` + "\n"
+
+	for _, mode := range []string{"", "fail"} {
+		parser.OnError = mode
+		require.NoError(t, parser.Init())
+		metrics, err := parser.ParseReader(strings.NewReader(input), nil)
+		require.EqualError(t, err, `line 2: measurement 'order', field 'amount' (path 'amount'): Unable to convert field 'amount' to type float: strconv.ParseFloat: parsing "n/a": invalid syntax`)
+		require.Empty(t, metrics)
+	}
+
+	parser.OnError = "skip"
+	require.NoError(t, parser.Init())
+	metrics, err := parser.ParseReader(strings.NewReader(input), nil)
+	require.EqualError(t, err, `skipped 1 of 3 lines with errors: line 2: skipped 1 of 1 configurations with errors: measurement 'order', field 'amount' (path 'amount'): Unable to convert field 'amount' to type float: strconv.ParseFloat: parsing "n/a": invalid syntax`)
+	require.Len(t, metrics, 2)
+	require.Equal(t, map[string]string{"id": "a"}, metrics[0].Tags())
+	require.Equal(t, map[string]string{"id": "c"}, metrics[1].Tags())
+
+	parser.OnError = "ignore"
+	require.EqualError(t, parser.Init(), "invalid on_error mode 'ignore', must be 'fail' or 'skip'")
+}
+
+func TestOnMissing(t *testing.T) {
+	newParser := func(mode string) *json_v2.Parser {
+		return &json_v2.Parser{
+			Configs: []json_v2.Config{
+				{
+					MeasurementName: "sensor",
+					Tags:            []json_v2.DataSet{{Path: "id", Optional: true}},
+					Fields: []json_v2.DataSet{
+						{Path: "temperature", Type: "float"},
+						{Path: "humidity", Type: "float", DefaultValue: "0"},
+						{Path: "battery", Type: "int", Optional: true},
+					},
+				},
+			},
+			OnMissing: mode,
+			Log:       testutil.Logger{},
+		}
+	}
+
+	for _, mode := range []string{"", "skip", "error"} {
+		t.Run(mode, func(t *testing.T) {
+			parser := newParser(mode)
+			require.NoError(t, parser.Init())
+
+			// Optional and default values never fail
+			metrics, err := parser.Parse([]byte(`{"id":"a","temperature":21.5}`))
+			require.NoError(t, err)
+			require.Len(t, metrics, 1)
+			require.Equal(t, map[string]interface{}{"temperature": 21.5, "humidity": float64(0)}, metrics[0].Fields())
+
+			metrics, err = parser.Parse([]byte(`{"id":"b","temperature":null,"humidity":40}`))
+			if mode == "error" {
+				require.EqualError(t, err, "measurement 'sensor', field 'temperature' (path 'temperature'): value is missing")
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, metrics, 1)
+			require.Equal(t, map[string]interface{}{"humidity": float64(40)}, metrics[0].Fields())
+		})
+	}
+
+	// The direct lookup of plain keys fails alike
+	parser := &json_v2.Parser{
+		Configs: []json_v2.Config{
+			{
+				MeasurementName: "sensor",
+				Fields:          []json_v2.DataSet{{Path: "temperature"}, {Path: "battery", Optional: true}},
+			},
+		},
+		OnMissing: "error",
+		Log:       testutil.Logger{},
+	}
+	require.NoError(t, parser.Init())
+	_, err := parser.Parse([]byte(`{"battery":80}`))
+	require.EqualError(t, err, "measurement 'sensor', field 'temperature' (path 'temperature'): value is missing")
+
+	parser = newParser("default")
+	require.EqualError(t, parser.Init(), "field 'temperature' requires a default_value or optional with on_missing 'default'")
+	parser.Configs[0].Fields[0].DefaultValue = "-273.15"
+	require.NoError(t, parser.Init())
+	metrics, err := parser.Parse([]byte(`{"id":"a"}`))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	require.Equal(t, map[string]interface{}{"temperature": -273.15, "humidity": float64(0)}, metrics[0].Fields())
+
+	parser.OnMissing = "ignore"
+	require.EqualError(t, parser.Init(), "invalid on_missing mode 'ignore', must be 'skip', 'error' or 'default'")
+}
+
+func TestOnErrorConfigurations(t *testing.T) {
+	parser := &json_v2.Parser{
+		Configs: []json_v2.Config{
+			{
+				MeasurementName: "good",
+				Fields: []json_v2.DataSet{
+					{Path: "value"},
+				},
+			},
+			{
+				MeasurementName: "bad",
+				Fields: []json_v2.DataSet{
+					{Path: "state", Type: "int"},
+				},
+			},
+		},
+		OnError: "skip",
+		Strict:  true,
+		Log:     testutil.Logger{},
+	}
+
+	metrics, err := parser.Parse([]byte(`{"value":1,"state":"unknown"}`))
+	require.EqualError(t, err, `skipped 1 of 2 configurations with errors: measurement 'bad', field 'state' (path 'state'): Unable to convert field 'state' to type int: strconv.ParseInt: parsing "unknown": invalid syntax`)
+	require.Len(t, metrics, 1)
+	require.Equal(t, "good", metrics[0].Name())
+}
+
+type csvSubParser struct{}
+
+func (*csvSubParser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	fields := make(map[string]interface{})
+	for i, v := range strings.Split(string(buf), ",") {
+		fields[fmt.Sprintf("col%d", i)] = v
+	}
+	return []telegraf.Metric{testutil.MustMetric("csv", map[string]string{}, fields, time.Unix(0, 0))}, nil
+}
+
+func TestSubParser(t *testing.T) {
+	parser := &json_v2.Parser{
+		Configs: []json_v2.Config{
+			{
+				MeasurementName: "record",
+				Fields: []json_v2.DataSet{
+					{Path: "row", SubParser: &csvSubParser{}, FlattenSeparator: "."},
+				},
+			},
+		},
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, parser.Init())
+
+	metrics, err := parser.Parse([]byte(`{"row":"a,b"}`))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	require.Equal(t, map[string]interface{}{"row.col0": "a", "row.col1": "b"}, metrics[0].Fields())
+
+	parser.Configs[0].Fields[0] = json_v2.DataSet{Path: "row", SubFormat: "xml"}
+	require.EqualError(t, parser.Init(), "invalid sub_format 'xml' of 'row', must be 'logfmt'")
+}
+
+func TestForceFloat(t *testing.T) {
+	parser := &json_v2.Parser{
+		Configs: []json_v2.Config{
+			{
+				MeasurementName: "request",
+				Fields: []json_v2.DataSet{
+					{Path: "count"},
+					{Path: "size", Type: "int"},
+					{Path: "message", SubFormat: "logfmt"},
+				},
+			},
+		},
+		TypeInference: true,
+		ForceFloat:    true,
+		Log:           testutil.Logger{},
+	}
+	require.NoError(t, parser.Init())
+
+	metrics, err := parser.Parse([]byte(`{"count":3,"size":512,"message":"duration=12 status=ok"}`))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	require.Equal(t, map[string]interface{}{
+		"count":            float64(3),
+		"size":             int64(512),
+		"message_duration": float64(12),
+		"message_status":   "ok",
+	}, metrics[0].Fields())
+
+	// Integers become floats also without type inference, including the values of an enumeration
+	parser.TypeInference = false
+	parser.Configs[0].Fields = []json_v2.DataSet{
+		{Path: "count"},
+		{Path: "level", EnumMap: map[string]int64{"info": 1, "error": 2}},
+		{Path: "size", Type: "uint"},
+	}
+	require.NoError(t, parser.Init())
+
+	metrics, err = parser.Parse([]byte(`{"count":3,"level":"error","size":512}`))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	require.Equal(t, map[string]interface{}{
+		"count": float64(3),
+		"level": float64(2),
+		"size":  uint64(512),
+	}, metrics[0].Fields())
+}
+
+func TestDropEmptyMetrics(t *testing.T) {
+	parser := &json_v2.Parser{
+		Configs: []json_v2.Config{
+			{
+				MeasurementName:  "sensor",
+				DropEmptyMetrics: true,
+				Fields: []json_v2.DataSet{
+					{Path: "temperature"},
+					{Path: "humidity"},
+				},
+				Tags: []json_v2.DataSet{
+					{Path: "location"},
+				},
+			},
+		},
+		Log: testutil.Logger{},
+	}
+
+	input := []byte(`{"location":"kitchen","pressure":1013}`)
+	metrics, err := parser.Parse(input)
+	require.NoError(t, err)
+	require.Empty(t, metrics)
+
+	parser.Configs[0].DropEmptyMetrics = false
+	metrics, err = parser.Parse(input)
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	require.Equal(t, map[string]string{"location": "kitchen"}, metrics[0].Tags())
+	require.Empty(t, metrics[0].Fields())
+}
+
+func TestNestedJSONMalformed(t *testing.T) {
+	parser := &json_v2.Parser{
+		Configs: []json_v2.Config{
+			{
+				MeasurementName: "pump",
+				Fields: []json_v2.DataSet{
+					{Path: "meta->rpm", ParseNestedJSON: true},
+					{Path: "load"},
+				},
+			},
+		},
+		Strict: true,
+		Log:    testutil.Logger{},
+	}
+
+	input := []byte(`{"meta":"{\"rpm\":","load":0.5}`)
+	_, err := parser.Parse(input)
+	require.EqualError(t, err, "measurement 'pump', field 'rpm' (path 'meta->rpm'): value at 'meta' holds invalid JSON")
+
+	parser.Strict = false
+	metrics, err := parser.Parse(input)
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	require.Equal(t, map[string]interface{}{"load": 0.5}, metrics[0].Fields())
+}
+
+func TestNumericStringsInvalid(t *testing.T) {
+	for _, input := range []string{`"0xZZ"`, `"1.5e0"`, `"0b"`} {
+		parser := &json_v2.Parser{
+			Configs: []json_v2.Config{
+				{
+					MeasurementName: "firmware",
+					Fields: []json_v2.DataSet{
+						{Path: "value", Type: "int"},
+					},
+				},
+			},
+			Strict: true,
+			Log:    testutil.Logger{},
+		}
+		_, err := parser.Parse([]byte(`{"value":` + input + `}`))
+		require.Error(t, err, input)
+	}
+}
+
+func TestStrictBool(t *testing.T) {
+	parser := &json_v2.Parser{
+		Configs: []json_v2.Config{
+			{
+				MeasurementName: "relay",
+				Fields: []json_v2.DataSet{
+					{Path: "state", Type: "bool", StrictBool: true},
+				},
+			},
+		},
+		Strict: true,
+		Log:    testutil.Logger{},
+	}
+
+	_, err := parser.Parse([]byte(`{"state":5}`))
+	require.EqualError(t, err, "measurement 'relay', field 'state' (path 'state'): Unable to convert field 'state' to type bool")
+}
+
+func TestObjectMatch(t *testing.T) {
+	parser := &json_v2.Parser{
+		Configs: []json_v2.Config{
+			{
+				MeasurementName: "host",
+				Fields: []json_v2.DataSet{
+					{Path: "load"},
+					{Path: "cpu"},
+				},
+			},
+		},
+		StrictObjects: true,
+		Log:           testutil.Logger{},
+	}
+
+	input := []byte(`{"load":0.5,"cpu":{"user":1.5,"system":0.5}}`)
+	_, err := parser.Parse(input)
+	require.EqualError(t, err, "measurement 'host', field 'cpu' (path 'cpu'): path matched an object, use type 'json', 'flatten' or an 'object' to gather it")
+
+	// Objects within a matched array are handled the same way
+	_, err = parser.Parse([]byte(`{"load":0.5,"cpu":[1.5,{"user":1.5}]}`))
+	require.EqualError(t, err, "measurement 'host', field 'cpu' (path 'cpu'): path matched an object, use type 'json', 'flatten' or an 'object' to gather it")
+
+	// Otherwise the object is skipped instead of being added as a stringified map, also in strict mode
+	parser.StrictObjects = false
+	parser.Strict = true
+	metrics, err := parser.Parse(input)
+	require.NoError(t, err)
+	expected := []telegraf.Metric{
+		testutil.MustMetric("host", map[string]string{}, map[string]interface{}{"load": 0.5}, time.Unix(0, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics, testutil.IgnoreTime())
+
+	// Setting the type json or flatten gathers the object
+	parser.StrictObjects = true
+	parser.Configs[0].Fields[1].Type = "json"
+	metrics, err = parser.Parse(input)
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	require.Equal(t, `{"user":1.5,"system":0.5}`, metrics[0].Fields()["cpu"])
+
+	parser.Configs[0].Fields[1].Type = ""
+	parser.Configs[0].Fields[1].Flatten = true
+	metrics, err = parser.Parse(input)
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	require.Equal(t, map[string]interface{}{"load": 0.5, "cpu_user": 1.5, "cpu_system": 0.5}, metrics[0].Fields())
+}
+
+func TestOverflow(t *testing.T) {
+	input := []byte(`{"big":1e20,"small":"-99999999999999999999","negative":-5,"negative_string":"-5","valid":42}`)
+	newParser := func(strict bool, overflow string) *json_v2.Parser {
+		return &json_v2.Parser{
+			Configs: []json_v2.Config{
+				{
+					MeasurementName: "counters",
+					Fields: []json_v2.DataSet{
+						{Path: "big", Type: "int", Overflow: overflow},
+						{Path: "small", Type: "int", Overflow: overflow},
+						{Path: "negative", Type: "uint", Overflow: overflow},
+						{Path: "negative_string", Type: "uint", Overflow: overflow},
+						{Path: "valid", Type: "uint", Overflow: overflow},
+					},
+				},
+			},
+			Strict: strict,
+			Log:    testutil.Logger{},
+		}
+	}
+
+	parser := newParser(true, "")
+	_, err := parser.Parse(input)
+	require.EqualError(t, err, "measurement 'counters', field 'big' (path 'big'): Unable to convert field 'big' to type int: value 1e+20 is out of range")
+
+	parser.Configs[0].Fields = parser.Configs[0].Fields[2:3]
+	_, err = parser.Parse(input)
+	require.EqualError(t, err, "measurement 'counters', field 'negative' (path 'negative'): Unable to convert field 'negative' to type uint: value -5 is out of range")
+
+	metrics, err := newParser(false, "skip").Parse(input)
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	require.Equal(t, map[string]interface{}{"valid": uint64(42)}, metrics[0].Fields())
+
+	expected := map[string]interface{}{
+		"big":             int64(math.MaxInt64),
+		"small":           int64(math.MinInt64),
+		"negative":        uint64(0),
+		"negative_string": uint64(0),
+		"valid":           uint64(42),
+	}
+	// Clamping applies regardless of strict mode
+	for _, strict := range []bool{true, false} {
+		metrics, err = newParser(strict, "clamp").Parse(input)
+		require.NoError(t, err)
+		require.Len(t, metrics, 1)
+		require.Equal(t, expected, metrics[0].Fields())
+	}
+
+	require.EqualError(t, newParser(false, "wrap").Init(), "invalid overflow mode 'wrap' of 'big', must be 'skip' or 'clamp'")
+}
+
+func TestMaxBodySizeAndDepth(t *testing.T) {
+	parser := &json_v2.Parser{
+		Configs: []json_v2.Config{
+			{
+				MeasurementName: "request",
+				Fields: []json_v2.DataSet{
+					{Path: "value"},
+				},
+			},
+		},
+		MaxBodySize: 64,
+		MaxDepth:    3,
+		Log:         testutil.Logger{},
+	}
+
+	metrics, err := parser.Parse([]byte(`{"value":1,"nested":{"list":[2],"text":"[[[{{{"}}`))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+
+	_, err = parser.Parse([]byte(`{"value":1,"padding":"` + strings.Repeat("x", 64) + `"}`))
+	require.EqualError(t, err, "input of 88 bytes exceeds the maximum body size of 64 bytes")
+
+	_, err = parser.Parse([]byte(`{"value":1,"nested":[[{"deep":true}]]}`))
+	require.EqualError(t, err, "input exceeds the maximum depth of 3 nested objects or arrays")
+
+	// The depth is checked while scanning, so the remainder of the input doesn't matter
+	parser.MaxBodySize = 0
+	_, err = parser.Parse([]byte(strings.Repeat("[", 100000)))
+	require.EqualError(t, err, "input exceeds the maximum depth of 3 nested objects or arrays")
+}
+
+func TestStripPrefixRegex(t *testing.T) {
+	parser := &json_v2.Parser{
+		Configs: []json_v2.Config{
+			{
+				MeasurementName: "app",
+				Fields: []json_v2.DataSet{
+					{Path: "x"},
+				},
+			},
+		},
+		StripPrefixRegex: `[^{]*`,
+		Log:              testutil.Logger{},
+	}
+	require.NoError(t, parser.Init())
+
+	m, err := parser.ParseLine(`2021-01-01 app[123]: {"x":1}`)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"x": 1.0}, m.Fields())
+
+	// Lines without a prefix are decoded as they are
+	m, err = parser.ParseLine(`{"x":2}`)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"x": 2.0}, m.Fields())
+
+	_, err = parser.ParseLine(`2021-01-01 app[123]: starting`)
+	require.EqualError(t, err, "Invalid JSON provided, unable to parse")
+
+	parser.StripPrefixRegex = `(`
+	require.EqualError(t, parser.Init(), "invalid strip_prefix_regex '(': error parsing regexp: missing closing ): `^(?:()`")
+}
+
+func TestStringToBool(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+		err      string
+	}{
+		{input: `"1"`, expected: true},
+		{input: `"0"`, expected: false},
+		{input: `"true"`, expected: true},
+		{input: `"false"`, expected: false},
+		{input: `"TRUE"`, expected: true},
+		{input: `"False"`, expected: false},
+		{input: `"t"`, expected: true},
+		{input: `"F"`, expected: false},
+		{input: `"yes"`, err: `Unable to convert field 'state' to type bool: invalid value "yes"`},
+		{input: `"no"`, err: `Unable to convert field 'state' to type bool: invalid value "no"`},
+		{input: `""`, err: `Unable to convert field 'state' to type bool: invalid value ""`},
+		{input: `"2"`, err: `Unable to convert field 'state' to type bool: invalid value "2"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			parser := &json_v2.Parser{
+				Configs: []json_v2.Config{
+					{
+						MeasurementName:  "switch",
+						DropEmptyMetrics: true,
+						Fields: []json_v2.DataSet{
+							{Path: "state", Type: "bool"},
+						},
+					},
+				},
+				Strict: true,
+				Log:    testutil.Logger{},
+			}
+
+			input := []byte(`{"state":` + tt.input + `}`)
+			metrics, err := parser.Parse(input)
+			if tt.err != "" {
+				require.EqualError(t, err, "measurement 'switch', field 'state' (path 'state'): "+tt.err)
+
+				// Values that are no bool are skipped if the parser isn't strict
+				parser.Strict = false
+				metrics, err = parser.Parse(input)
+				require.NoError(t, err)
+				require.Empty(t, metrics)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, metrics, 1)
+			require.Equal(t, map[string]interface{}{"state": tt.expected}, metrics[0].Fields())
+		})
+	}
+}
+
+func TestInitInvalidPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   json_v2.Config
+		expected string
+	}{
+		{
+			name:     "unbalanced query",
+			config:   json_v2.Config{Fields: []json_v2.DataSet{{Path: `servers.#(name=="web"`, Rename: "web"}}},
+			expected: `json_v2[0] (measurement 'cpu'), field 'web': invalid path 'servers.#(name=="web"': missing closing ')'`,
+		},
+		{
+			name:     "unexpected bracket",
+			config:   json_v2.Config{Tags: []json_v2.DataSet{{Path: "hosts.0]"}}},
+			expected: `json_v2[0] (measurement 'cpu'), tag '0]': invalid path 'hosts.0]': unexpected ']' at position 8`,
+		},
+		{
+			name:     "empty key",
+			config:   json_v2.Config{Fields: []json_v2.DataSet{{Path: "usage..idle"}}},
+			expected: `json_v2[0] (measurement 'cpu'), field 'idle': invalid path 'usage..idle': empty key at position 7`,
+		},
+		{
+			name:     "trailing separator in fallback path",
+			config:   json_v2.Config{Fields: []json_v2.DataSet{{Path: "usage", FallbackPaths: []string{"stats."}}}},
+			expected: `json_v2[0] (measurement 'cpu'), field 'usage': invalid path 'stats.': path ends with a separator`,
+		},
+		{
+			name:     "unknown modifier",
+			config:   json_v2.Config{Fields: []json_v2.DataSet{{Path: "usage|@revers", Rename: "usage"}}},
+			expected: `json_v2[0] (measurement 'cpu'), field 'usage': invalid path 'usage|@revers': unknown modifier '@revers'`,
+		},
+		{
+			name:     "timestamp path",
+			config:   json_v2.Config{TimestampPath: `time.#(unit=="s)`},
+			expected: `json_v2[0] (measurement 'cpu'), timestamp_path: invalid path 'time.#(unit=="s)': missing closing '"'`,
+		},
+		{
+			name:     "empty filter path",
+			config:   json_v2.Config{Filters: []json_v2.Filter{{Value: "ok"}}},
+			expected: `json_v2[0] (measurement 'cpu'), filter: invalid path '': path is empty`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.MeasurementName = "cpu"
+			parser := &json_v2.Parser{
+				Configs: []json_v2.Config{tt.config},
+				Log:     testutil.Logger{},
+			}
+			require.EqualError(t, parser.Init(), tt.expected)
+		})
+	}
+
+	parser := &json_v2.Parser{
+		Configs: []json_v2.Config{
+			{
+				MeasurementName: "cpu_{host}",
+				TimestampPath:   "time",
+				RootPath:        "data.result[0]",
+				Fields: []json_v2.DataSet{
+					{Path: `servers.#(name=="web.1").load`},
+					{Path: "$..idle"},
+					{Path: `meta->stats.\.hidden`, ParseNestedJSON: true},
+					{Path: "values|@reverse"},
+					{Path: "{usage,idle}"},
+				},
+			},
+		},
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, parser.Init())
+}
+
+func TestLocaleNumbersMalformed(t *testing.T) {
+	parser := &json_v2.Parser{
+		Configs: []json_v2.Config{
+			{
+				MeasurementName: "europe",
+				Fields: []json_v2.DataSet{
+					{Path: "amount", Type: "float", DecimalSeparator: ",", GroupSeparator: "."},
+				},
+			},
+		},
+		Strict: true,
+		Log:    testutil.Logger{},
+	}
+
+	_, err := parser.Parse([]byte(`{"amount":"1,234,56"}`))
+	require.Error(t, err)
+
+	parser.Strict = false
+	metrics, err := parser.Parse([]byte(`{"amount":"1,234,56"}`))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	require.Empty(t, metrics[0].Fields())
+}
+
+func TestGoldenLineProtocol(t *testing.T) {
+	parser := &json_v2.Parser{
+		Configs: []json_v2.Config{
+			{
+				MeasurementName: "servers",
+				TimestampPath:   "time",
+				TimestampFormat: "unix",
+				Tags: []json_v2.DataSet{
+					{Path: "region"},
+				},
+				JSONObjects: []json_v2.JSONObject{
+					{
+						Path:    "servers",
+						Tags:    []string{"name"},
+						Renames: map[string]string{"name": "host"},
+						Fields:  map[string]string{"connections": "int"},
+					},
+				},
+			},
+		},
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, parser.Init())
+
+	input := []byte(`{
+		"region": "eu west",
+		"time": 1609459200,
+		"servers": [
+			{"name": "a", "load": 0.5, "up": true, "connections": "12", "version": "1.0 \"beta\""},
+			{"name": "b", "load": 1, "up": false, "connections": 3, "version": "2.1"}
+		]
+	}`)
+	metrics, err := parser.Parse(input)
+	require.NoError(t, err)
+
+	expected := `servers,host=a,region=eu\ west load=0.5,up=true,connections=12i,version="1.0 \"beta\"" 1609459200000000000
+servers,host=b,region=eu\ west load=1,up=false,connections=3i,version="2.1" 1609459200000000000
+`
+	require.Equal(t, expected, lineProtocol(t, metrics))
+}
+
+// lineProtocol serializes the metrics to influx line protocol in their order and with the fields in the order they
+// were added, to compare the output of the parser with golden strings. Unlike parsing the expected lines with the
+// influx parser this also catches differences in the field order and formatting.
+func lineProtocol(t *testing.T, metrics []telegraf.Metric) string {
+	t.Helper()
+	b, err := influxSerializer.NewSerializer().SerializeBatch(metrics)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func readMetricFile(path string) ([]telegraf.Metric, error) {
+	var metrics []telegraf.Metric
+	expectedFile, err := os.Open(path)
+	if err != nil {
+		return metrics, err
+	}
+	defer expectedFile.Close()
+
+	parser := influx.NewParser(influx.NewMetricHandler())
+	scanner := bufio.NewScanner(expectedFile)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line != "" {
+			m, err := parser.ParseLine(line)
+			if err != nil {
+				return nil, fmt.Errorf("unable to parse metric in %q failed: %v", line, err)
+			}
+			metrics = append(metrics, m)
+		}
+	}
+	err = expectedFile.Close()
+	if err != nil {
+		return metrics, err
+	}
+
+	return metrics, nil
+}
+
+func benchmarkConfigs(n int) []json_v2.Config {
+	configs := make([]json_v2.Config, 0, n)
+	for i := 0; i < n; i++ {
+		configs = append(configs, json_v2.Config{
+			MeasurementName: fmt.Sprintf("servers_%d", i),
+			TimestampPath:   "time",
+			TimestampFormat: "unix",
+			Tags: []json_v2.DataSet{
+				{Path: "host"},
+				{Path: "region"},
+			},
+			Fields: []json_v2.DataSet{
+				{Path: "servers.63.load", Rename: "load"},
+				{Path: fmt.Sprintf("stats.counter_%d", i%4), Rename: "counter"},
+			},
+		})
+	}
+	return configs
+}
+
+func TestLookupWithoutInit(t *testing.T) {
+	// Inputs like tail create a parser for every file without calling Init, so the file is loaded on parsing
+	parser, err := parsers.NewParser(&parsers.Config{
+		DataFormat: "json_v2",
+		JSONV2Config: []parsers.JSONV2Config{
+			{
+				Config: json_v2.Config{
+					MeasurementName: "server",
+					Tags:            []json_v2.DataSet{{Path: "host"}},
+					Fields:          []json_v2.DataSet{{Path: "load"}},
+					Lookups:         []json_v2.Lookup{{Tag: "host", File: "testdata/lookup/lookup.json"}},
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	metrics, err := parser.Parse([]byte(`{"host":"server02","load":0.5}`))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	require.Equal(t, map[string]string{"host": "server02", "datacenter": "us-west", "rack": "12"}, metrics[0].Tags())
+}
+
+func TestRequireMonotonicNegative(t *testing.T) {
+	parser := &json_v2.Parser{
+		Configs: []json_v2.Config{
+			{
+				MeasurementName:  "sensor",
+				DropEmptyMetrics: true,
+				Fields: []json_v2.DataSet{
+					{Path: "temperature", RequireMonotonic: true},
+				},
+			},
+		},
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, parser.Init())
+
+	// A negative first value following an element without a value is kept
+	metrics, err := parser.Parse([]byte(`{"temperature":[null,-5,-3,-4,-1]}`))
+	require.NoError(t, err)
+	var values []interface{}
+	for _, m := range metrics {
+		values = append(values, m.Fields()["temperature"])
+	}
+	require.Equal(t, []interface{}{-5.0, -3.0, -1.0}, values)
+}
+
+func TestDeltaWithoutInit(t *testing.T) {
+	// Inputs like tail create a parser for every file without calling Init
+	parser, err := parsers.NewParser(&parsers.Config{
+		DataFormat: "json_v2",
+		JSONV2Config: []parsers.JSONV2Config{
+			{
+				Config: json_v2.Config{
+					MeasurementName: "server",
+					Fields:          []json_v2.DataSet{{Path: "requests", Type: "int", Delta: true}},
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	metrics, err := parser.Parse([]byte(`{"requests":10}`))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	require.Empty(t, metrics[0].Fields())
+
+	metrics, err = parser.Parse([]byte(`{"requests":15}`))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	require.Equal(t, map[string]interface{}{"requests": int64(5)}, metrics[0].Fields())
+}
+
+func TestDelta(t *testing.T) {
+	parser := &json_v2.Parser{
+		Configs: []json_v2.Config{
+			{
+				MeasurementName:  "server",
+				DropEmptyMetrics: true,
+				Tags:             []json_v2.DataSet{{Path: "host"}},
+				Fields: []json_v2.DataSet{
+					{Path: "requests", Type: "int", Delta: true},
+					{Path: "errors", Delta: true, DeltaReset: "zero"},
+				},
+			},
+		},
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, parser.Init())
+
+	tests := []struct {
+		input    string
+		expected []telegraf.Metric
+	}{
+		{
+			// The first value of a series has no previous value
+			input: `{"host":"a","requests":100,"errors":5}`,
+		},
+		{
+			input: `{"host":"a","requests":130,"errors":7}`,
+			expected: []telegraf.Metric{
+				testutil.MustMetric("server", map[string]string{"host": "a"}, map[string]interface{}{"requests": int64(30), "errors": 2.0}, time.Unix(0, 0)),
+			},
+		},
+		{
+			input: `{"host":"b","requests":10,"errors":1}`,
+		},
+		{
+			// The counters were reset
+			input: `{"host":"a","requests":20,"errors":3}`,
+			expected: []telegraf.Metric{
+				testutil.MustMetric("server", map[string]string{"host": "a"}, map[string]interface{}{"requests": int64(20), "errors": 0.0}, time.Unix(0, 0)),
+			},
+		},
+		{
+			input: `{"host":"b","requests":15,"errors":4}`,
+			expected: []telegraf.Metric{
+				testutil.MustMetric("server", map[string]string{"host": "b"}, map[string]interface{}{"requests": int64(5), "errors": 3.0}, time.Unix(0, 0)),
+			},
+		},
+	}
+	for _, tt := range tests {
+		actual, err := parser.Parse([]byte(tt.input))
+		require.NoError(t, err)
+		testutil.RequireMetricsEqual(t, tt.expected, actual, testutil.IgnoreTime())
+	}
+}
+
+func TestLiteralValues(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	parser := &json_v2.Parser{
+		Configs: []json_v2.Config{
+			{
+				MeasurementName: "event",
+				Tags:            []json_v2.DataSet{{Value: "1.2.3", Rename: "version"}},
+				Fields: []json_v2.DataSet{
+					{Path: "value"},
+					{Value: "@now", Rename: "ingested"},
+					{Value: "@line", Rename: "line"},
+					{Value: "42", Rename: "answer", Type: "int"},
+				},
+			},
+		},
+		TimeFunc: func() time.Time { return now },
+		Log:      testutil.Logger{},
+	}
+	require.NoError(t, parser.Init())
+
+	// The line number is only known when parsing line by line
+	actual, err := parser.Parse([]byte(`{"value":1.5}`))
+	require.NoError(t, err)
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"event",
+			map[string]string{"version": "1.2.3"},
+			map[string]interface{}{"value": 1.5, "ingested": now.Unix(), "answer": int64(42)},
+			now,
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+
+	actual, err = parser.ParseReader(strings.NewReader("{\"value\":1.5}\n{\"value\":2.5}\n"), nil)
+	require.NoError(t, err)
+	expected = []telegraf.Metric{
+		testutil.MustMetric(
+			"event",
+			map[string]string{"version": "1.2.3"},
+			map[string]interface{}{"value": 1.5, "ingested": now.Unix(), "line": int64(1), "answer": int64(42)},
+			now,
+		),
+		testutil.MustMetric(
+			"event",
+			map[string]string{"version": "1.2.3"},
+			map[string]interface{}{"value": 2.5, "ingested": now.Unix(), "line": int64(2), "answer": int64(42)},
+			now,
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+
+	parser = &json_v2.Parser{
+		Configs: []json_v2.Config{{Fields: []json_v2.DataSet{{Value: "@now"}}}},
+		Log:     testutil.Logger{},
+	}
+	require.EqualError(t, parser.Init(), "json_v2[0] (measurement ''), field with value '@now' requires rename and must not have a path")
+}
+
+func TestMaxMetrics(t *testing.T) {
+	var values []string
+	for i := 0; i < 10; i++ {
+		values = append(values, strconv.Itoa(i))
+	}
+	input := []byte(`{"values":[` + strings.Join(values, ",") + `]}`)
+
+	newParser := func(n int, mode string) *json_v2.Parser {
+		parser := &json_v2.Parser{
+			Configs: []json_v2.Config{
+				{
+					MeasurementName: "sample",
+					SampleEveryN:    2,
+					Fields:          []json_v2.DataSet{{Path: "values", Type: "int"}},
+				},
+			},
+			MaxMetrics:     n,
+			MaxMetricsMode: mode,
+			Log:            testutil.Logger{},
+		}
+		require.NoError(t, parser.Init())
+		return parser
+	}
+
+	// The limit applies to the metrics kept after sampling every second element
+	metrics, err := newParser(5, "fail").Parse(input)
+	require.NoError(t, err)
+	require.Len(t, metrics, 5)
+
+	_, err = newParser(3, "").Parse(input)
+	require.EqualError(t, err, "input resulted in 5 metrics, more than the maximum of 3")
+
+	metrics, err = newParser(3, "truncate").Parse(input)
+	require.NoError(t, err)
+	expected := []telegraf.Metric{
+		testutil.MustMetric("sample", map[string]string{}, map[string]interface{}{"values": int64(0)}, time.Unix(0, 0)),
+		testutil.MustMetric("sample", map[string]string{}, map[string]interface{}{"values": int64(2)}, time.Unix(0, 0)),
+		testutil.MustMetric("sample", map[string]string{}, map[string]interface{}{"values": int64(4)}, time.Unix(0, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics, testutil.IgnoreTime())
+
+	parser := &json_v2.Parser{MaxMetricsMode: "drop", Log: testutil.Logger{}}
+	require.EqualError(t, parser.Init(), "invalid max_metrics_mode 'drop', must be 'fail' or 'truncate'")
+}
+
+func TestFormatMessagePack(t *testing.T) {
+	// Run the configurations of existing tests against MessagePack encoded versions of their JSON inputs
+	for _, test := range []string{"fields_and_tags", "multiple_arrays_in_object", "types"} {
+		t.Run(test, func(t *testing.T) {
+			input, err := ioutil.ReadFile(fmt.Sprintf("testdata/%s/input.json", test))
+			require.NoError(t, err)
+			filename := filepath.Join(t.TempDir(), "input.msgpack")
+			require.NoError(t, ioutil.WriteFile(filename, jsonToMsgpack(nil, gjson.ParseBytes(input)), 0644))
+
+			buf, err := ioutil.ReadFile(fmt.Sprintf("testdata/%s/telegraf.conf", test))
+			require.NoError(t, err)
+			conf := strings.ReplaceAll(string(buf), fmt.Sprintf("./testdata/%s/input.json", test), filename)
+			conf = strings.ReplaceAll(conf, `data_format = "json_v2"`, `data_format = "json_v2"`+"\n"+`json_v2_format = "msgpack"`)
+			require.Contains(t, conf, filename)
+
+			inputs.Add("file", func() telegraf.Input {
+				return &file.File{}
+			})
+			cfg := config.NewConfig()
+			require.NoError(t, cfg.LoadConfigData([]byte(conf)))
+
+			acc := testutil.Accumulator{}
+			for _, i := range cfg.Inputs {
+				require.NoError(t, i.Init())
+				require.NoError(t, i.Gather(&acc))
+			}
+
+			expected, err := readMetricFile(fmt.Sprintf("testdata/%s/expected.out", test))
+			require.NoError(t, err)
+			testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+		})
+	}
+}
+
+// jsonToMsgpack encodes the JSON value as MessagePack, keeping the order of the keys and integers as integers
+func jsonToMsgpack(b []byte, v gjson.Result) []byte {
+	switch {
+	case v.IsObject():
+		values := v.Map()
+		b = msgp.AppendMapHeader(b, uint32(len(values)))
+		v.ForEach(func(key, value gjson.Result) bool {
+			b = msgp.AppendString(b, key.String())
+			b = jsonToMsgpack(b, value)
+			return true
+		})
+	case v.IsArray():
+		values := v.Array()
+		b = msgp.AppendArrayHeader(b, uint32(len(values)))
+		for _, value := range values {
+			b = jsonToMsgpack(b, value)
+		}
+	case v.Type == gjson.Number:
+		if i, err := strconv.ParseInt(v.Raw, 10, 64); err == nil {
+			return msgp.AppendInt64(b, i)
+		}
+		b = msgp.AppendFloat64(b, v.Num)
+	case v.Type == gjson.String:
+		b = msgp.AppendString(b, v.Str)
+	case v.Type == gjson.True || v.Type == gjson.False:
+		b = msgp.AppendBool(b, v.Bool())
+	default:
+		b = msgp.AppendNil(b)
+	}
+	return b
+}
+
+func TestFormatCBOR(t *testing.T) {
+	input := []byte{
+		0xa5,                     // map with 5 pairs
+		0x64, 'h', 'o', 's', 't', // "host"
+		0x68, 's', 'e', 'r', 'v', 'e', 'r', '0', '1', // "server01"
+		0x64, 'l', 'o', 'a', 'd', // "load"
+		0xf9, 0x38, 0x00, // half-precision 0.5
+		0x65, 'c', 'o', 'u', 'n', 't', // "count"
+		0x18, 0x64, // 100
+		0x64, 'd', 'i', 'f', 'f', // "diff"
+		0x29,                          // -10
+		0x65, 'b', 'a', 't', 'c', 'h', // "batch"
+		0x9f, 0xfb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0, 0xf5, 0xff, // indefinite array [1.5, true]
+	}
+	parser := &json_v2.Parser{
+		Format: "cbor",
+		Configs: []json_v2.Config{
+			{
+				MeasurementName: "server",
+				Tags:            []json_v2.DataSet{{Path: "host"}},
+				Fields: []json_v2.DataSet{
+					{Path: "load"},
+					{Path: "count", Type: "int"},
+					{Path: "diff", Type: "int"},
+					{Path: "batch", Type: "json"},
+				},
+			},
+		},
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, parser.Init())
+
+	actual, err := parser.Parse(input)
+	require.NoError(t, err)
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"server",
+			map[string]string{"host": "server01"},
+			map[string]interface{}{"load": 0.5, "count": int64(100), "diff": int64(-10), "batch": "[1.5,true]"},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual, testutil.IgnoreTime())
+
+	_, err = parser.Parse(input[:len(input)-1])
+	require.EqualError(t, err, "decoding CBOR failed: unexpected end of the CBOR document at byte 54")
+
+	parser = &json_v2.Parser{Format: "yaml", Log: testutil.Logger{}}
+	require.EqualError(t, parser.Init(), "invalid format 'yaml', must be 'json', 'msgpack' or 'cbor'")
+}
+
+func TestDebugLogging(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	log.SetOutput(wlog.NewWriter(buf))
+	level := wlog.LogLevel()
+	defer func() {
+		log.SetOutput(os.Stderr)
+		wlog.SetLevel(level)
+	}()
+
+	parser := &json_v2.Parser{
+		Configs: []json_v2.Config{
+			{
+				MeasurementName: "server",
+				Fields:          []json_v2.DataSet{{Path: "load", Type: "float"}},
+				Tags:            []json_v2.DataSet{{Path: "host"}},
+			},
+		},
+		Log: testutil.Logger{Name: "parsers.json_v2"},
+	}
+	require.NoError(t, parser.Init())
+	input := []byte(`{"host":"server01","load":"0.5"}`)
+
+	// Nothing is logged above debug level
+	wlog.SetLevel(wlog.INFO)
+	_, err := parser.Parse(input)
+	require.NoError(t, err)
+	require.Empty(t, buf.String())
+
+	wlog.SetLevel(wlog.DEBUG)
+	_, err = parser.Parse(input)
+	require.NoError(t, err)
+	require.Contains(t, buf.String(), `D! [parsers.json_v2] Extracted measurement="server" field="load" query="load" matched="0.5" value="0.5" type=float64`)
+	require.Contains(t, buf.String(), `D! [parsers.json_v2] Extracted measurement="server" tag="host" query="host" matched="server01" value="server01" type=string`)
+}
+
+// BenchmarkParseConfigs compares a single configuration with several configurations sharing the timestamp and tag
+// queries against the same document
+func BenchmarkParseConfigs(b *testing.B) {
+	var buf strings.Builder
+	buf.WriteString(`{"host":"server01","region":"eu","time":1609459200,"stats":{`)
+	for i := 0; i < 32; i++ {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		fmt.Fprintf(&buf, `"counter_%d":%d`, i, i)
+	}
+	buf.WriteString(`},"servers":[`)
+	for i := 0; i < 64; i++ {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		fmt.Fprintf(&buf, `{"name":"server%02d","load":%d.5}`, i, i)
+	}
+	buf.WriteString(`]}`)
+	input := []byte(buf.String())
+
+	for _, n := range []int{1, 8} {
+		b.Run(fmt.Sprintf("configs_%d", n), func(b *testing.B) {
+			parser := &json_v2.Parser{
+				Configs: benchmarkConfigs(n),
+				Log:     testutil.Logger{},
+			}
+			require.NoError(b, parser.Init())
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, err := parser.Parse(input)
+				require.NoError(b, err)
+			}
+		})
+	}
 }
@@ -2,6 +2,7 @@ package parsers
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/plugins/parsers/collectd"
@@ -166,7 +167,29 @@ type Config struct {
 	XPathConfig        []XPathConfig
 
 	// JSONPath configuration
-	JSONV2Config []JSONV2Config `toml:"json_v2"`
+	JSONV2Config             []JSONV2Config `toml:"json_v2"`
+	JSONV2AllFieldsAsStrings bool           `toml:"json_v2_all_fields_as_strings"`
+	JSONV2Strict             bool           `toml:"json_v2_strict"`
+	JSONV2StrictObjects      bool           `toml:"-"`
+	JSONV2TimePrecision      time.Duration  `toml:"json_v2_time_precision"`
+	JSONV2TypeInference      bool           `toml:"json_v2_type_inference"`
+	JSONV2OnError            string         `toml:"json_v2_on_error"`
+	JSONV2ForceFloat         bool           `toml:"json_v2_force_float"`
+
+	JSONV2ParseErrorsMeasurement   string `toml:"json_v2_parse_errors_measurement"`
+	JSONV2ParseErrorsSnippetLength int    `toml:"json_v2_parse_errors_snippet_length"`
+	JSONV2MaxBodySize              int    `toml:"json_v2_max_body_size"`
+	JSONV2MaxDepth                 int    `toml:"json_v2_max_depth"`
+	JSONV2StripPrefixRegex         string `toml:"json_v2_strip_prefix_regex"`
+	JSONV2TrimSpace                bool   `toml:"json_v2_trim_space"`
+	JSONV2Dedup                    bool   `toml:"json_v2_dedup"`
+	JSONV2InvalidUTF8              string `toml:"json_v2_invalid_utf8"`
+	JSONV2UnicodeNormalization     string `toml:"json_v2_unicode_normalization"`
+	JSONV2Format                   string `toml:"json_v2_format"`
+	JSONV2MaxMetrics               int    `toml:"json_v2_max_metrics"`
+	JSONV2MaxMetricsMode           string `toml:"json_v2_max_metrics_mode"`
+	JSONV2JSONLines                bool   `toml:"json_v2_json_lines"`
+	JSONV2OnMissing                string `toml:"json_v2_on_missing"`
 }
 
 type XPathConfig xpath.Config
@@ -272,7 +295,7 @@ func NewParser(config *Config) (Parser, error) {
 			Configs:             NewXPathParserConfigs(config.MetricName, config.XPathConfig),
 		}
 	case "json_v2":
-		parser, err = NewJSONPathParser(config.JSONV2Config)
+		parser, err = NewJSONPathParser(config)
 	default:
 		err = fmt.Errorf("Invalid data format: %s", config.DataFormat)
 	}
@@ -401,22 +424,66 @@ func NewXPathParserConfigs(metricName string, cfgs []XPathConfig) []xpath.Config
 	return configs
 }
 
-func NewJSONPathParser(jsonv2config []JSONV2Config) (Parser, error) {
-	configs := make([]json_v2.Config, len(jsonv2config))
-	for i, cfg := range jsonv2config {
+func NewJSONPathParser(config *Config) (Parser, error) {
+	configs := make([]json_v2.Config, len(config.JSONV2Config))
+	for i, cfg := range config.JSONV2Config {
 		configs[i].MeasurementName = cfg.MeasurementName
 		configs[i].MeasurementNamePath = cfg.MeasurementNamePath
 
 		configs[i].TimestampPath = cfg.TimestampPath
 		configs[i].TimestampFormat = cfg.TimestampFormat
+		configs[i].TimestampFormats = cfg.TimestampFormats
 		configs[i].TimestampTimezone = cfg.TimestampTimezone
+		configs[i].TimestampDefault = cfg.TimestampDefault
+		configs[i].TotalCountField = cfg.TotalCountField
+		configs[i].Unwrap = cfg.Unwrap
+		configs[i].EmitMatchCoverage = cfg.EmitMatchCoverage
+		configs[i].ExpiryTag = cfg.ExpiryTag
+		configs[i].ExpiryAfter = cfg.ExpiryAfter
+		configs[i].DropEmptyMetrics = cfg.DropEmptyMetrics
+		configs[i].IndexTag = cfg.IndexTag
+		configs[i].DefaultFieldName = cfg.DefaultFieldName
+		configs[i].DefaultTags = cfg.DefaultTags
+		configs[i].RootPath = cfg.RootPath
+		configs[i].SampleEveryN = cfg.SampleEveryN
+		configs[i].EmitCount = cfg.EmitCount
+		configs[i].CountMeasurement = cfg.CountMeasurement
+		configs[i].HashTag = cfg.HashTag
+		configs[i].HashKeys = cfg.HashKeys
+		configs[i].HashAlgorithm = cfg.HashAlgorithm
+		configs[i].TagPrecedence = cfg.TagPrecedence
 
 		configs[i].Fields = cfg.Fields
 		configs[i].Tags = cfg.Tags
 
 		configs[i].JSONObjects = cfg.JSONObjects
+		configs[i].Filters = cfg.Filters
+		configs[i].Lookups = cfg.Lookups
+		configs[i].Groups = cfg.Groups
 	}
 	return &json_v2.Parser{
-		Configs: configs,
+		Configs:            configs,
+		AllFieldsAsStrings: config.JSONV2AllFieldsAsStrings,
+		Strict:             config.JSONV2Strict,
+		StrictObjects:      config.JSONV2StrictObjects,
+		TimePrecision:      config.JSONV2TimePrecision,
+		TypeInference:      config.JSONV2TypeInference,
+		OnError:            config.JSONV2OnError,
+		ForceFloat:         config.JSONV2ForceFloat,
+
+		ParseErrorsMeasurement:   config.JSONV2ParseErrorsMeasurement,
+		ParseErrorsSnippetLength: config.JSONV2ParseErrorsSnippetLength,
+		MaxBodySize:              config.JSONV2MaxBodySize,
+		MaxDepth:                 config.JSONV2MaxDepth,
+		StripPrefixRegex:         config.JSONV2StripPrefixRegex,
+		TrimSpace:                config.JSONV2TrimSpace,
+		Dedup:                    config.JSONV2Dedup,
+		InvalidUTF8:              config.JSONV2InvalidUTF8,
+		UnicodeNormalization:     config.JSONV2UnicodeNormalization,
+		Format:                   config.JSONV2Format,
+		MaxMetrics:               config.JSONV2MaxMetrics,
+		MaxMetricsMode:           config.JSONV2MaxMetricsMode,
+		JSONLines:                config.JSONV2JSONLines,
+		OnMissing:                config.JSONV2OnMissing,
 	}, nil
 }